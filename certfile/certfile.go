@@ -0,0 +1,274 @@
+package certfile
+
+// Loader of certificate chains from local files: PEM, DER, PKCS#12 and
+// Kubernetes TLS secret manifests
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// NameSeparator separates file path and bundle name in certificate
+// address, e.g. "secrets.yaml#prod/web-tls"
+const NameSeparator = "#"
+
+const kubeTLSSecretType = "kubernetes.io/tls"
+
+// ErrNoCertificates is returned for files which hold no certificates
+var ErrNoCertificates = errors.New("no certificates found")
+
+// Bundle is a certificate chain loaded from file. Leaf certificate goes
+// first.
+type Bundle struct {
+	// Name identifies bundle within file which holds several of them.
+	// It's a "namespace/name" of Kubernetes secret.
+	Name         string
+	Certificates []*x509.Certificate
+}
+
+// Leaf returns leaf certificate of the chain
+func (b *Bundle) Leaf() *x509.Certificate {
+	return b.Certificates[0]
+}
+
+// Address returns certificate address of the bundle loaded from path
+func (b *Bundle) Address(path string) string {
+	if b.Name == "" {
+		return path
+	}
+	return path + NameSeparator + b.Name
+}
+
+// IsCertFile checks if file extension is one of recognized certificate or
+// manifest file extensions
+func IsCertFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pem", ".crt", ".cer", ".der", ".p12", ".pfx", ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+// Load reads bundle by address produced by Bundle.Address
+func Load(address, password string) (*Bundle, error) {
+	path, name, _ := strings.Cut(address, NameSeparator)
+	bundles, err := LoadAll(path, password)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, b := range bundles {
+		if b.Name == name || name == "" && len(bundles) == 1 {
+			return b, nil
+		}
+	}
+	if name == "" {
+		return nil, fmt.Errorf("%q holds %d certificate bundles, bundle name is required", path, len(bundles))
+	}
+	return nil, fmt.Errorf("bundle %q not found in %q", name, path)
+}
+
+// LoadAll reads all certificate bundles from file. Format is chosen by
+// extension: ".p12" and ".pfx" for PKCS#12, ".yaml", ".yml" and ".json"
+// for Kubernetes secret manifests. Other files may hold either PEM or DER
+// certificates.
+func LoadAll(path, password string) ([]*Bundle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var bundles []*Bundle
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".p12", ".pfx":
+		certs, err := parsePKCS12(data, password)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse PKCS#12 file %q: %w", path, err)
+		}
+		bundles = []*Bundle{{Certificates: certs}}
+	case ".yaml", ".yml", ".json":
+		bundles, err = parseSecrets(data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse secret manifest %q: %w", path, err)
+		}
+	default:
+		certs, err := parseCertificates(data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse certificate file %q: %w", path, err)
+		}
+		bundles = []*Bundle{{Certificates: certs}}
+	}
+
+	if len(bundles) == 0 {
+		return nil, fmt.Errorf("%q: %w", path, ErrNoCertificates)
+	}
+	return bundles, nil
+}
+
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	if !bytes.Contains(data, []byte("-----BEGIN")) {
+		return orderChain(x509.ParseCertificates(data))
+	}
+
+	var der []byte
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			der = append(der, block.Bytes...)
+		}
+	}
+	return orderChain(x509.ParseCertificates(der))
+}
+
+// parsePKCS12 extracts certificate chain from PKCS#12 file, either one
+// with a private key or a trust store holding certificates only
+func parsePKCS12(data []byte, password string) ([]*x509.Certificate, error) {
+	_, leaf, chain, err := pkcs12.DecodeChain(data, password)
+	if err != nil {
+		certs, storeErr := pkcs12.DecodeTrustStore(data, password)
+		if storeErr != nil {
+			return nil, err
+		}
+		return orderChain(certs, nil)
+	}
+	return orderChain(append([]*x509.Certificate{leaf}, chain...), nil)
+}
+
+// parseSecrets extracts certificates from all Kubernetes TLS secrets of
+// the manifest. Other objects are skipped without decoding.
+func parseSecrets(data []byte) ([]*Bundle, error) {
+	var bundles []*Bundle
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var node yaml.Node
+		err := dec.Decode(&node)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		docBundles, err := decodeSecretNode(&node)
+		if err != nil {
+			return nil, err
+		}
+		bundles = append(bundles, docBundles...)
+	}
+
+	return bundles, nil
+}
+
+func decodeSecretNode(node *yaml.Node) ([]*Bundle, error) {
+	var header struct {
+		Kind  string      `yaml:"kind"`
+		Type  string      `yaml:"type"`
+		Items []yaml.Node `yaml:"items"`
+	}
+	if err := node.Decode(&header); err != nil {
+		// not a Kubernetes object at all
+		return nil, nil
+	}
+
+	if strings.HasSuffix(header.Kind, "List") {
+		var bundles []*Bundle
+		for i := range header.Items {
+			itemBundles, err := decodeSecretNode(&header.Items[i])
+			if err != nil {
+				return nil, err
+			}
+			bundles = append(bundles, itemBundles...)
+		}
+		return bundles, nil
+	}
+
+	if header.Kind != "Secret" || header.Type != kubeTLSSecretType {
+		return nil, nil
+	}
+
+	var secret secretManifest
+	if err := node.Decode(&secret); err != nil {
+		return nil, err
+	}
+	b, err := secret.bundle()
+	if err != nil {
+		return nil, fmt.Errorf("secret %s: %w", secret.name(), err)
+	}
+	return []*Bundle{b}, nil
+}
+
+type secretManifest struct {
+	Metadata struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+	Data       map[string]string `yaml:"data"`
+	StringData map[string]string `yaml:"stringData"`
+}
+
+func (s *secretManifest) name() string {
+	ns := s.Metadata.Namespace
+	if ns == "" {
+		ns = "default"
+	}
+	return ns + "/" + s.Metadata.Name
+}
+
+func (s *secretManifest) bundle() (*Bundle, error) {
+	var data []byte
+	if crt, ok := s.StringData["tls.crt"]; ok {
+		data = []byte(crt)
+	} else if crt, ok := s.Data["tls.crt"]; ok {
+		var err error
+		data, err = base64.StdEncoding.DecodeString(crt)
+		if err != nil {
+			return nil, fmt.Errorf("bad tls.crt encoding: %w", err)
+		}
+	} else {
+		return nil, errors.New("tls.crt is missing")
+	}
+
+	certs, err := parseCertificates(data)
+	if err != nil {
+		return nil, err
+	}
+	return &Bundle{
+		Name:         s.name(),
+		Certificates: certs,
+	}, nil
+}
+
+// orderChain moves leaf certificate to the front. Leaf is the first
+// certificate which is not CA, or the first one if all of them are CAs.
+func orderChain(certs []*x509.Certificate, err error) ([]*x509.Certificate, error) {
+	if err != nil {
+		return nil, err
+	}
+	if len(certs) == 0 {
+		return nil, ErrNoCertificates
+	}
+
+	for i, cert := range certs {
+		if !cert.IsCA {
+			res := append([]*x509.Certificate{cert}, certs[:i]...)
+			return append(res, certs[i+1:]...), nil
+		}
+	}
+	return certs, nil
+}
@@ -0,0 +1,147 @@
+package certfile
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// testChain returns leaf certificate issued by CA certificate and leaf key
+func testChain(t *testing.T) (leaf, ca *x509.Certificate, key crypto.PrivateKey) {
+	t.Helper()
+
+	issue := func(tmpl, parent *x509.Certificate, pub, signer interface{}) *x509.Certificate {
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, pub, signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert
+	}
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	ca = issue(caTmpl, caTmpl, &caKey.PublicKey, caKey)
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf = issue(&x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "www.example.com"},
+		DNSNames:     []string{"www.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}, ca, &leafKey.PublicKey, caKey)
+
+	return leaf, ca, leafKey
+}
+
+func pemCerts(certs ...*x509.Certificate) []byte {
+	var res []byte
+	for _, cert := range certs {
+		res = append(res, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+	}
+	return res
+}
+
+func TestLoadAll(t *testing.T) {
+	leaf, ca, key := testChain(t)
+	encode := func(enc *pkcs12.Encoder, password string) []byte {
+		data, err := enc.Encode(key, leaf, []*x509.Certificate{ca}, password)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	trustStore, err := pkcs12.Modern.EncodeTrustStore([]*x509.Certificate{ca}, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := "apiVersion: v1\nkind: Secret\ntype: kubernetes.io/tls\n" +
+		"metadata:\n  name: web-tls\n  namespace: shop\n" +
+		"data:\n  tls.crt: " + base64.StdEncoding.EncodeToString(pemCerts(ca, leaf)) + "\n" +
+		"---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: other\n"
+
+	tests := []struct {
+		name     string
+		file     string
+		data     []byte
+		password string
+		wantName string
+		want     []*x509.Certificate
+		wantErr  bool
+	}{
+		{name: "PEM chain is ordered", file: "chain.pem", data: pemCerts(ca, leaf), want: []*x509.Certificate{leaf, ca}},
+		{name: "DER", file: "leaf.der", data: leaf.Raw, want: []*x509.Certificate{leaf}},
+		{name: "PKCS#12 with AES", file: "modern.p12", data: encode(pkcs12.Modern, "secret"), password: "secret", want: []*x509.Certificate{leaf, ca}},
+		{name: "PKCS#12 with RC2", file: "legacy.pfx", data: encode(pkcs12.LegacyRC2, "secret"), password: "secret", want: []*x509.Certificate{leaf, ca}},
+		{name: "PKCS#12 without password", file: "plain.p12", data: encode(pkcs12.Passwordless, ""), want: []*x509.Certificate{leaf, ca}},
+		{name: "PKCS#12 trust store", file: "store.p12", data: trustStore, password: "secret", want: []*x509.Certificate{ca}},
+		{name: "PKCS#12 with wrong password", file: "modern.p12", data: encode(pkcs12.Modern, "secret"), password: "wrong", wantErr: true},
+		{name: "secret manifest", file: "secret.yaml", data: []byte(secret), wantName: "shop/web-tls", want: []*x509.Certificate{leaf, ca}},
+		{name: "no certificates", file: "empty.pem", data: []byte("nothing here"), wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.file)
+			if err := os.WriteFile(path, tc.data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			bundles, err := LoadAll(path, tc.password)
+			if tc.wantErr {
+				if err == nil {
+					t.Error("error expected")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(bundles) != 1 {
+				t.Fatalf("got %d bundles, want 1", len(bundles))
+			}
+			if bundles[0].Name != tc.wantName {
+				t.Errorf("got bundle name %q, want %q", bundles[0].Name, tc.wantName)
+			}
+			got := bundles[0].Certificates
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %d certificates, want %d", len(got), len(tc.want))
+			}
+			for i := range got {
+				if !got[i].Equal(tc.want[i]) {
+					t.Errorf("certificate #%d is %s, want %s", i, got[i].Subject, tc.want[i].Subject)
+				}
+			}
+		})
+	}
+}
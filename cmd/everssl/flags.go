@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// stringList is a flag value which accumulates all occurrences of the flag
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func stringListFlag(name, usage string) *stringList {
+	l := new(stringList)
+	flag.Var(l, name, usage)
+	return l
+}
+
+// splitList parses comma-separated list dropping empty elements
+func splitList(s string) []string {
+	var res []string
+	for _, elem := range strings.Split(s, ",") {
+		elem = strings.TrimSpace(elem)
+		if elem != "" {
+			res = append(res, elem)
+		}
+	}
+	return res
+}
+
+// splitCommand parses command line given either as a JSON array of
+// arguments or as words separated with spaces
+func splitCommand(s string) ([]string, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "[") {
+		return strings.Fields(s), nil
+	}
+
+	var args []string
+	if err := json.Unmarshal([]byte(s), &args); err != nil {
+		return nil, fmt.Errorf("bad JSON array of command arguments: %w", err)
+	}
+	if len(args) > 0 && args[0] == "" {
+		return nil, fmt.Errorf("command name is empty")
+	}
+	return args, nil
+}
@@ -5,14 +5,18 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net"
 	"os"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/mysteriumnetwork/everssl/enumerator"
+	"github.com/mysteriumnetwork/everssl/enumerator/cfhelper"
 	"github.com/mysteriumnetwork/everssl/heartbeat"
 	"github.com/mysteriumnetwork/everssl/reporter"
 	"github.com/mysteriumnetwork/everssl/validator"
+	"github.com/mysteriumnetwork/everssl/validator/result"
 	"github.com/mysteriumnetwork/everssl/workflow"
 )
 
@@ -26,20 +30,72 @@ var (
 	retries     = flag.Int("retries", 3, "validation retries")
 
 	// enumerator options
-	CFAPIToken = flag.String("cf-api-token", "", "Cloudflare API token")
-	scanIPv6   = flag.Bool("6", true, "scan IPv6 origins")
-	ignoreRE   = flag.String("ignore", `\b\B`, "regular expressions which matching domains to ignore")
+	CFAPIToken         = flag.String("cf-api-token", "", "Cloudflare API token")
+	scanIPv6           = flag.Bool("6", true, "scan IPv6 origins")
+	scanMX             = flag.Bool("mx", false, "scan mail exchangers with SMTP STARTTLS")
+	targetsFile        = flag.String("targets-file", "", "inventory file with targets (YAML, JSON or plain text with one domain[:port][@address] per line)")
+	zoneFiles          = stringListFlag("zone-file", "zone file to enumerate in form [ORIGIN=]PATH (may be repeated)")
+	axfrPrimary        = flag.String("axfr-primary", "", "primary DNS server (host[:port]) to transfer zones from")
+	axfrZones          = flag.String("axfr-zones", "", "comma-separated list of zones to transfer from AXFR primary when \"__all__\" is requested")
+	axfrTSIGName       = flag.String("axfr-tsig-name", "", "TSIG key name for zone transfers")
+	axfrTSIGAlgorithm  = flag.String("axfr-tsig-algorithm", "hmac-sha256", "TSIG algorithm for zone transfers")
+	axfrTSIGSecret     = flag.String("axfr-tsig-secret", "", "base64-encoded TSIG secret for zone transfers")
+	ctLog              = flag.String("ct-log", "", "Certificate Transparency log URL or path to local log dump to discover hostnames from")
+	ctDomains          = flag.String("ct-domains", "", "comma-separated list of registered domains looked up in CT log when \"__all__\" is requested")
+	ctStart            = flag.Int64("ct-start", -10000, "first CT log entry to scan, negative values are counted from the end of log")
+	ctCount            = flag.Int64("ct-count", -1, "number of CT log entries to scan, negative means up to the end of log")
+	kubeSource         = flag.String("kube", "", "Kubernetes API server URL or directory with manifests to read Ingress, Gateway and HTTPRoute objects from")
+	kubeToken          = flag.String("kube-token", "", "bearer token for Kubernetes API server")
+	kubeCAFile         = flag.String("kube-ca-file", "", "file with CA certificates of Kubernetes API server")
+	certFiles          = stringListFlag("cert-file", "certificate file (PEM, DER, PKCS#12 or Kubernetes TLS secret manifest) or directory with them to check without handshake (may be repeated)")
+	pkcs12Password     = flag.String("pkcs12-password", "", "password of PKCS#12 certificate files")
+	powerDNSURL        = flag.String("powerdns-url", "", "PowerDNS Authoritative Server API base URL, e.g. \"http://127.0.0.1:8081\"")
+	powerDNSAPIKey     = flag.String("powerdns-api-key", "", "PowerDNS API key")
+	powerDNSServer     = flag.String("powerdns-server", enumerator.DefaultPowerDNSServerID, "PowerDNS server ID")
+	doToken            = flag.String("do-token", "", "DigitalOcean API token to enumerate DigitalOcean DNS domains")
+	hetznerToken       = flag.String("hetzner-token", "", "Hetzner DNS API token to enumerate Hetzner DNS zones")
+	execCommand        = flag.String("exec", "", "external command printing targets as newline-delimited JSON, zone name is passed as the last argument (JSON array of arguments or words split on spaces, no shell is involved)")
+	execTimeout        = flag.Duration("exec-timeout", enumerator.DefaultExecTimeout, "run time limit of external enumerator command")
+	cfConcurrency      = flag.Int("cf-concurrency", 8, "number of Cloudflare zones enumerated concurrently")
+	cfCustomHostnames  = flag.Bool("cf-custom-hostnames", false, "enumerate Cloudflare for SaaS custom hostnames")
+	cfSpectrum         = flag.Bool("cf-spectrum", false, "enumerate Cloudflare Spectrum applications")
+	resolveOrigins     = flag.Bool("resolve-origins", false, "resolve hostname origins of DNS provider records and Cloudflare load balancers and check every IP address")
+	cfAccounts         = flag.String("cf-accounts", "", "comma-separated list of Cloudflare account IDs to enumerate zones from")
+	cfZoneGlobs        = flag.String("cf-zones", "", "comma-separated list of glob patterns of Cloudflare zone names to enumerate")
+	cfZoneStatuses     = flag.String("cf-zone-status", "", "comma-separated list of Cloudflare zone statuses to enumerate, e.g. \"active\"")
+	cfZonePlans        = flag.String("cf-zone-plans", "", "comma-separated list of Cloudflare zone plans to enumerate, e.g. \"free,enterprise\"")
+	cfExcludeZones     = flag.String("cf-exclude-zones", "", "comma-separated list of glob patterns of Cloudflare zone names to skip")
+	proxyPorts         = flag.String("cf-proxy-ports", "443", "comma-separated list of ports to check on Cloudflare edge for proxied hostnames")
+	cacheFile          = flag.String("cache-file", "", "file to cache enumerated targets in, cached targets are used if live enumeration fails")
+	cacheMaxAge        = flag.Duration("cache-max-age", enumerator.DefaultCacheMaxAge, "maximal age of cached targets used on enumeration failure, zero means no limit")
+	wildcardLabels     = flag.String("wildcard-labels", "", "comma-separated list of sample labels substituted into wildcard names, synthetic label is used if empty")
+	tolerateEnumErrors = flag.Bool("tolerate-enumerator-errors", false, "continue with remaining target sources if some of them fail")
+	ignoreRE           = flag.String("ignore", `\b\B`, "regular expressions which matching domains to ignore")
 
 	// validator options
 	expireTreshold = flag.Duration("expire-treshold", 14*24*time.Hour, "expiration alarm treshold")
+	clockSkew      = flag.Duration("clock-skew", 0, "tolerance of certificates which become valid later than local clock says")
 	rateLimitEvery = flag.Duration("rate-every", 100*time.Millisecond, "ratelimit period (inverse of frequency)")
 	verify         = flag.Bool("verify", true, "verify certificates")
+	expectStapling = flag.Bool("expect-stapling", false, "warn if OCSP response is not stapled, stale or not good")
+	stapleValidity = flag.Duration("staple-min-validity", time.Hour, "minimal remaining validity of stapled OCSP response")
+	trustStores    = stringListFlag("trust-store", "named trust store in form NAME=PATH[,PATH...] with PEM CA bundles added to system roots, store named \"default\" is used for targets without assigned store (may be repeated)")
+	exclStores     = stringListFlag("exclusive-trust-store", "named trust store in form NAME=PATH[,PATH...] with PEM CA bundles replacing system roots (may be repeated)")
+	zoneStores     = stringListFlag("zone-trust-store", "trust store assignment in form ZONE=NAME for targets in zone, \"system\" means system roots (may be repeated)")
+	checkRevoked   = flag.Bool("check-revocation", false, "check revocation status of certificates with OCSP and CRL")
 
 	// error filter options
 	ignoreConnectionErrors   = flag.Bool("ignore-connection-errors", true, "ignore connection errors")
 	ignoreHandshakeErrors    = flag.Bool("ignore-handshake-errors", true, "ignore handshake errors")
 	ignoreVerificationErrors = flag.Bool("ignore-verification-errors", true, "ignore certificate verification errors")
 	ignoreExpirationErrors   = flag.Bool("ignore-expiration-errors", false, "ignore expiration errors")
+	ignoreEnumerationErrors  = flag.Bool("ignore-enumeration-errors", false, "ignore target enumeration errors")
+	ignoreProviderErrors     = flag.Bool("ignore-provider-errors", false, "ignore certificate problems reported by provider (e.g. Cloudflare for SaaS)")
+	ignoreRevocationErrors   = flag.Bool("ignore-revocation-errors", false, "ignore certificate revocation errors")
+	ignoreStaplingErrors     = flag.Bool("ignore-stapling-errors", false, "ignore OCSP stapling warnings")
+	ignoreNotYetValidErrors  = flag.Bool("ignore-not-yet-valid-errors", false, "ignore certificates which are not valid yet")
+	ignoreCacheWarnings      = flag.Bool("ignore-cache-warnings", false, "ignore warnings about zones checked using cached targets")
+	ignoreLoadErrors         = flag.Bool("ignore-load-errors", false, "ignore errors loading local certificate files")
 
 	// reporter options
 	pagerDutyKey  = flag.String("pagerduty-key", "", "PagerDuty Events V2 integration key")
@@ -62,9 +118,54 @@ func run() int {
 		}
 	}
 
-	if *CFAPIToken == "" {
+	if *axfrTSIGSecret == "" {
+		envToken := os.Getenv("AXFR_TSIG_SECRET")
+		if envToken != "" {
+			*axfrTSIGSecret = envToken
+		}
+	}
+
+	if *kubeToken == "" {
+		envToken := os.Getenv("KUBE_TOKEN")
+		if envToken != "" {
+			*kubeToken = envToken
+		}
+	}
+
+	if *pkcs12Password == "" {
+		envToken := os.Getenv("PKCS12_PASSWORD")
+		if envToken != "" {
+			*pkcs12Password = envToken
+		}
+	}
+
+	if *powerDNSAPIKey == "" {
+		envToken := os.Getenv("POWERDNS_API_KEY")
+		if envToken != "" {
+			*powerDNSAPIKey = envToken
+		}
+	}
+
+	if *doToken == "" {
+		envToken := os.Getenv("DIGITALOCEAN_TOKEN")
+		if envToken != "" {
+			*doToken = envToken
+		}
+	}
+
+	if *hetznerToken == "" {
+		envToken := os.Getenv("HETZNER_DNS_TOKEN")
+		if envToken != "" {
+			*hetznerToken = envToken
+		}
+	}
+
+	if *CFAPIToken == "" && *targetsFile == "" && len(*zoneFiles) == 0 && *axfrPrimary == "" &&
+		*ctLog == "" && *kubeSource == "" && len(*certFiles) == 0 && *execCommand == "" &&
+		*powerDNSURL == "" && *doToken == "" && *hetznerToken == "" {
 		log.Fatal("Cloudflare API token is not specified. Either set CF_API_TOKEN " +
-			"environment variable or specify -cf-api-token command line argument")
+			"environment variable or specify -cf-api-token command line argument " +
+			"or pass other target sources with -targets-file, -zone-file, -axfr-primary, -ct-log, -kube, -cert-file, -exec, -powerdns-url, -do-token or -hetzner-token command line arguments")
 	}
 
 	if *pagerDutyKey == "" {
@@ -91,10 +192,96 @@ func run() int {
 		log.Fatalf("domain ignore regexp compilation error: %v", err)
 	}
 
-	targetEnum, err := enumerator.NewCFEnumerator(*CFAPIToken)
+	var enumerators []enumerator.Enumerator
+	if *CFAPIToken != "" {
+		cfEnum, err := enumerator.NewCFEnumerator(*CFAPIToken)
+		if err != nil {
+			log.Fatalf("unable to construct CFEnumerator: %v", err)
+		}
+		cfEnum.SetScanMX(*scanMX).
+			SetProxyPorts(splitList(*proxyPorts)).
+			SetConcurrency(*cfConcurrency).
+			SetScanCustomHostnames(*cfCustomHostnames).
+			SetScanSpectrum(*cfSpectrum).
+			SetZoneFilter(cfhelper.ZoneFilter{
+				AccountIDs:   splitList(*cfAccounts),
+				NameGlobs:    splitList(*cfZoneGlobs),
+				Statuses:     splitList(*cfZoneStatuses),
+				Plans:        splitList(*cfZonePlans),
+				ExcludeGlobs: splitList(*cfExcludeZones),
+			})
+		if *resolveOrigins {
+			cfEnum.SetResolver(net.DefaultResolver)
+		}
+		enumerators = append(enumerators, cfEnum)
+	}
+	var providerEnums []*enumerator.DNSProviderEnumerator
+	if *powerDNSURL != "" {
+		providerEnums = append(providerEnums, enumerator.NewPowerDNSEnumerator(*powerDNSURL, *powerDNSAPIKey, *powerDNSServer))
+	}
+	if *doToken != "" {
+		providerEnums = append(providerEnums, enumerator.NewDigitalOceanEnumerator(*doToken))
+	}
+	if *hetznerToken != "" {
+		providerEnums = append(providerEnums, enumerator.NewHetznerEnumerator(*hetznerToken))
+	}
+	for _, providerEnum := range providerEnums {
+		providerEnum.SetScanMX(*scanMX)
+		if *resolveOrigins {
+			providerEnum.SetResolver(net.DefaultResolver)
+		}
+		enumerators = append(enumerators, providerEnum)
+	}
+	if *targetsFile != "" {
+		enumerators = append(enumerators, enumerator.NewFileEnumerator(*targetsFile))
+	}
+	if len(*zoneFiles) > 0 {
+		enumerators = append(enumerators, enumerator.NewZoneFileEnumerator(*zoneFiles...).SetScanMX(*scanMX))
+	}
+	if *axfrPrimary != "" {
+		axfrEnum := enumerator.NewAXFREnumerator(*axfrPrimary, splitList(*axfrZones)).SetScanMX(*scanMX)
+		if *axfrTSIGName != "" {
+			axfrEnum.SetTSIG(*axfrTSIGName, *axfrTSIGAlgorithm, *axfrTSIGSecret)
+		}
+		enumerators = append(enumerators, axfrEnum)
+	}
+	if *ctLog != "" {
+		enumerators = append(enumerators,
+			enumerator.NewCTEnumerator(*ctLog, splitList(*ctDomains)).SetWindow(*ctStart, *ctCount))
+	}
+
+	if *kubeSource != "" {
+		kubeEnum := enumerator.NewKubeEnumerator(*kubeSource).SetToken(*kubeToken)
+		if *kubeCAFile != "" {
+			if _, err := kubeEnum.SetCAFile(*kubeCAFile); err != nil {
+				log.Fatalf("unable to construct KubeEnumerator: %v", err)
+			}
+		}
+		enumerators = append(enumerators, kubeEnum)
+	}
+
+	if len(*certFiles) > 0 {
+		enumerators = append(enumerators,
+			enumerator.NewCertFileEnumerator(*certFiles...).SetPKCS12Password(*pkcs12Password))
+	}
+
+	execArgs, err := splitCommand(*execCommand)
 	if err != nil {
-		log.Fatalf("unable to construct CFEnumerator: %v", err)
+		log.Fatalf("unable to parse -exec command: %v", err)
 	}
+	if len(execArgs) > 0 {
+		enumerators = append(enumerators,
+			enumerator.NewExecEnumerator(execArgs[0], execArgs[1:]...).SetTimeout(*execTimeout))
+	}
+
+	var targetEnum enumerator.Enumerator = enumerators[0]
+	if len(enumerators) > 1 {
+		targetEnum = enumerator.NewMultiEnumerator(enumerators...).SetTolerateErrors(*tolerateEnumErrors)
+	}
+	if *cacheFile != "" {
+		targetEnum = enumerator.NewCachingEnumerator(targetEnum, *cacheFile).SetMaxAge(*cacheMaxAge)
+	}
+	targetEnum = enumerator.NewWildcardEnumerator(targetEnum, splitList(*wildcardLabels)...)
 
 	ctx, cl := context.WithTimeout(context.Background(), *timeout)
 	defer cl()
@@ -105,7 +292,13 @@ func run() int {
 		*oneTimeout,
 		*retries,
 		*verify,
-	)
+	).SetPKCS12Password(*pkcs12Password).
+		SetCheckRevocation(*checkRevoked).
+		SetExpectStapling(*expectStapling, *stapleValidity).
+		SetClockSkew(*clockSkew)
+	if err := setupTrustStores(targetValidator); err != nil {
+		log.Fatalf("trust store configuration error: %v", err)
+	}
 
 	var drain reporter.Reporter
 	if *pagerDutyKey == "" {
@@ -125,14 +318,19 @@ func run() int {
 	}
 
 	runner := workflow.NewRunner(targetEnum, domainFilter, targetValidator, drain, beat)
-	err = runner.Run(ctx,
-		zones,
-		*scanIPv6,
-		*ignoreConnectionErrors,
-		*ignoreHandshakeErrors,
-		*ignoreVerificationErrors,
-		*ignoreExpirationErrors,
-	)
+	err = runner.Run(ctx, zones, *scanIPv6, map[result.ValidationErrorKind]bool{
+		result.ConnectionError:   *ignoreConnectionErrors,
+		result.HandshakeError:    *ignoreHandshakeErrors,
+		result.VerificationError: *ignoreVerificationErrors,
+		result.ExpirationError:   *ignoreExpirationErrors,
+		result.EnumerationError:  *ignoreEnumerationErrors,
+		result.ProviderError:     *ignoreProviderErrors,
+		result.LoadError:         *ignoreLoadErrors,
+		result.RevocationError:   *ignoreRevocationErrors,
+		result.StaplingError:     *ignoreStaplingErrors,
+		result.NotYetValidError:  *ignoreNotYetValidErrors,
+		result.FallbackError:     *ignoreCacheWarnings,
+	})
 	if err != nil {
 		log.Fatalf("workflow error: %v", err)
 	}
@@ -148,3 +346,45 @@ func main() {
 	log.Default().SetFlags(log.Ldate | log.Ltime | log.Lmicroseconds | log.Lshortfile)
 	os.Exit(run())
 }
+
+// setupTrustStores loads trust stores and assigns them to zones
+func setupTrustStores(v *validator.ConcurrentValidator) error {
+	known := map[string]bool{
+		validator.SystemTrustStore: true,
+	}
+	for _, specs := range []struct {
+		list      []string
+		exclusive bool
+	}{
+		{*trustStores, false},
+		{*exclStores, true},
+	} {
+		for _, spec := range specs.list {
+			name, paths, ok := strings.Cut(spec, "=")
+			if !ok || name == "" || len(splitList(paths)) == 0 {
+				return fmt.Errorf("bad trust store %q, NAME=PATH[,PATH...] expected", spec)
+			}
+			if known[name] {
+				return fmt.Errorf("duplicate trust store %q", name)
+			}
+			store, err := validator.LoadTrustStore(name, specs.exclusive, splitList(paths)...)
+			if err != nil {
+				return fmt.Errorf("unable to load trust store %q: %w", name, err)
+			}
+			v.AddTrustStore(store)
+			known[name] = true
+		}
+	}
+
+	for _, spec := range *zoneStores {
+		zone, name, ok := strings.Cut(spec, "=")
+		if !ok || zone == "" || name == "" {
+			return fmt.Errorf("bad zone trust store %q, ZONE=NAME expected", spec)
+		}
+		if !known[name] {
+			return fmt.Errorf("unknown trust store %q assigned to zone %s", name, zone)
+		}
+		v.SetZoneTrustStore(zone, name)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package enumerator
+
+// Enumerator which obtains zone contents via zone transfer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/miekg/dns"
+
+	"github.com/mysteriumnetwork/everssl/target"
+)
+
+const (
+	DefaultTSIGAlgorithm = dns.HmacSHA256
+	AXFRTimeout          = 30 * time.Second
+	tsigFudge            = 300
+)
+
+type AXFREnumerator struct {
+	primary       string
+	zones         []string
+	tsigName      string
+	tsigAlgorithm string
+	tsigSecret    string
+	scanMX        bool
+}
+
+// NewAXFREnumerator creates enumerator which transfers zones from primary
+// server given in "host[:port]" form. If zones list is not empty, only these
+// zones are transferred and they form the "__all__" zone set. Failed
+// transfers of "__all__" zones are reported as ZoneErrors, targets of other
+// zones are still returned.
+func NewAXFREnumerator(primary string, zones []string) *AXFREnumerator {
+	if _, _, err := net.SplitHostPort(primary); err != nil {
+		primary = net.JoinHostPort(primary, "53")
+	}
+
+	return &AXFREnumerator{
+		primary: primary,
+		zones:   zones,
+	}
+}
+
+// SetTSIG enables TSIG authentication of zone transfers. Secret is expected
+// in base64 form. Empty algorithm means HMAC-SHA256.
+func (e *AXFREnumerator) SetTSIG(name, algorithm, secret string) *AXFREnumerator {
+	if algorithm == "" {
+		algorithm = DefaultTSIGAlgorithm
+	}
+	e.tsigName = dns.CanonicalName(name)
+	e.tsigAlgorithm = dns.CanonicalName(algorithm)
+	e.tsigSecret = secret
+	return e
+}
+
+// SetScanMX enables enumeration of mail exchangers as SMTP targets
+func (e *AXFREnumerator) SetScanMX(scanMX bool) *AXFREnumerator {
+	e.scanMX = scanMX
+	return e
+}
+
+func (e *AXFREnumerator) Enumerate(ctx context.Context, zone string, ipv6 bool) ([]target.Target, error) {
+	var zones []string
+	switch {
+	case zone == AllZones:
+		if len(e.zones) == 0 {
+			return nil, fmt.Errorf("zones to transfer from %s as %s are not listed", e.primary, AllZones)
+		}
+		zones = e.zones
+	case len(e.zones) == 0:
+		zones = []string{zone}
+	default:
+		for _, z := range e.zones {
+			if strings.EqualFold(dns.Fqdn(z), dns.Fqdn(zone)) {
+				zones = []string{zone}
+				break
+			}
+		}
+	}
+
+	targets := make(map[target.Target]struct{})
+	var resultErr error
+	for _, z := range zones {
+		recs, err := e.transfer(ctx, z)
+		if err != nil {
+			err = fmt.Errorf("zone transfer of %q from %s failed: %w", z, e.primary, err)
+			if zone != AllZones {
+				return nil, err
+			}
+			resultErr = multierror.Append(resultErr, &ZoneError{Zone: z, Err: err})
+			continue
+		}
+
+		addRecordTargets(targets, recs, ipv6, e.scanMX, []string{DefaultPort},
+			fmt.Sprintf("AXFR of %s from %s", z, e.primary))
+	}
+
+	res := make([]target.Target, 0, len(targets))
+	for k := range targets {
+		res = append(res, k)
+	}
+
+	return target.Merge(res), resultErr
+}
+
+func (e *AXFREnumerator) transfer(ctx context.Context, zone string) ([]dnsRecord, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", e.primary)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// Transfer has no context support, so abort it by closing connection
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	tr := &dns.Transfer{
+		Conn:         &dns.Conn{Conn: conn},
+		ReadTimeout:  AXFRTimeout,
+		WriteTimeout: AXFRTimeout,
+	}
+
+	m := new(dns.Msg)
+	m.SetAxfr(dns.Fqdn(zone))
+	if e.tsigName != "" {
+		m.SetTsig(e.tsigName, e.tsigAlgorithm, tsigFudge, time.Now().Unix())
+		tr.TsigSecret = map[string]string{e.tsigName: e.tsigSecret}
+	}
+
+	envelopes, err := tr.In(m, e.primary)
+	if err != nil {
+		return nil, err
+	}
+
+	var recs []dnsRecord
+	for env := range envelopes {
+		if env.Error != nil {
+			return nil, env.Error
+		}
+		for _, rr := range env.RR {
+			if rec, ok := rrToRecord(rr); ok {
+				recs = append(recs, rec)
+			}
+		}
+	}
+
+	return recs, nil
+}
@@ -0,0 +1,234 @@
+package enumerator
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/miekg/dns"
+
+	"github.com/mysteriumnetwork/everssl/target"
+)
+
+const (
+	testTSIGName   = "transfer.example."
+	testTSIGSecret = "c2VjcmV0IGtleSBmb3IgdGVzdHM="
+)
+
+// startAXFRServer serves zone transfers of zones on local TCP port. If
+// tsig is set, unsigned requests are refused.
+func startAXFRServer(t *testing.T, zones map[string]string, tsig bool) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		q := req.Question[0]
+		zone, ok := zones[strings.ToLower(q.Name)]
+		if q.Qtype != dns.TypeAXFR || !ok || tsig && (req.IsTsig() == nil || w.TsigStatus() != nil) {
+			m := new(dns.Msg)
+			m.SetRcode(req, dns.RcodeRefused)
+			w.WriteMsg(m)
+			return
+		}
+
+		var rrs []dns.RR
+		zp := dns.NewZoneParser(strings.NewReader(zone), q.Name, "")
+		for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+			rrs = append(rrs, rr)
+		}
+		if err := zp.Err(); err != nil {
+			t.Errorf("bad test zone %s: %v", q.Name, err)
+			return
+		}
+		// transfer starts and ends with SOA
+		rrs = append(rrs, rrs[0])
+
+		ch := make(chan *dns.Envelope, 1)
+		tr := new(dns.Transfer)
+		if tsig {
+			tr.TsigSecret = map[string]string{testTSIGName: testTSIGSecret}
+		}
+		done := make(chan error, 1)
+		go func() {
+			done <- tr.Out(w, req, ch)
+		}()
+		ch <- &dns.Envelope{RR: rrs}
+		close(ch)
+		if err := <-done; err != nil {
+			t.Errorf("transfer failed: %v", err)
+		}
+		w.Close()
+	})
+
+	srv := &dns.Server{
+		Listener: l,
+		Handler:  handler,
+	}
+	if tsig {
+		srv.TsigSecret = map[string]string{testTSIGName: testTSIGSecret}
+	}
+	go srv.ActivateAndServe()
+	t.Cleanup(func() {
+		srv.Shutdown()
+	})
+
+	return l.Addr().String()
+}
+
+func TestAXFREnumerator(t *testing.T) {
+	zones := map[string]string{
+		"example.com.": `@ IN SOA ns1 hostmaster 1 7200 900 1209600 300
+@ IN MX 10 mail
+www IN A 192.0.2.10
+www IN AAAA 2001:db8::10
+api IN CNAME www
+`,
+		"example.org.": `@ IN SOA ns1 hostmaster 1 7200 900 1209600 300
+app IN A 198.51.100.1
+`,
+	}
+
+	tests := []struct {
+		name      string
+		tsig      bool
+		clientKey string
+		zones     []string
+		zone      string
+		ipv6      bool
+		scanMX    bool
+		want      []string
+		failed    []string
+		wantErr   bool
+	}{
+		{
+			name: "single zone",
+			zone: "example.com",
+			want: []string{"www.example.com@192.0.2.10", "api.example.com@www.example.com"},
+		},
+		{
+			name:   "IPv6 and MX",
+			zone:   "example.com",
+			ipv6:   true,
+			scanMX: true,
+			want: []string{
+				"www.example.com@192.0.2.10", "www.example.com@2001:db8::10",
+				"api.example.com@www.example.com", "mail.example.com:25/smtp",
+			},
+		},
+		{
+			name:  "all configured zones",
+			zones: []string{"example.com", "example.org"},
+			zone:  AllZones,
+			want: []string{
+				"www.example.com@192.0.2.10", "api.example.com@www.example.com",
+				"app.example.org@198.51.100.1",
+			},
+		},
+		{
+			name:   "failed zone among all configured zones",
+			zones:  []string{"example.com", "example.net"},
+			zone:   AllZones,
+			want:   []string{"www.example.com@192.0.2.10", "api.example.com@www.example.com"},
+			failed: []string{"example.net"},
+		},
+		{
+			name:    "all zones not configured",
+			zone:    AllZones,
+			wantErr: true,
+		},
+		{
+			name:  "zone not configured",
+			zones: []string{"example.org"},
+			zone:  "example.com",
+			want:  nil,
+		},
+		{
+			name:      "TSIG",
+			tsig:      true,
+			clientKey: testTSIGSecret,
+			zone:      "example.org",
+			want:      []string{"app.example.org@198.51.100.1"},
+		},
+		{
+			name:    "TSIG required",
+			tsig:    true,
+			zone:    "example.org",
+			wantErr: true,
+		},
+		{
+			name:      "bad TSIG key",
+			tsig:      true,
+			clientKey: "d3Jvbmcga2V5",
+			zone:      "example.org",
+			wantErr:   true,
+		},
+		{
+			name:    "refused",
+			zone:    "example.net",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			addr := startAXFRServer(t, zones, tc.tsig)
+			e := NewAXFREnumerator(addr, tc.zones).SetScanMX(tc.scanMX)
+			if tc.clientKey != "" {
+				e.SetTSIG(testTSIGName, "", tc.clientKey)
+			}
+
+			got, err := e.Enumerate(context.Background(), tc.zone, tc.ipv6)
+			if tc.wantErr {
+				if !IsFailure(err) {
+					t.Fatalf("failure expected, got %v", err)
+				}
+				return
+			}
+			if IsFailure(err) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var failed []string
+			if merr, ok := err.(*multierror.Error); ok {
+				for _, e := range merr.Errors {
+					failed = append(failed, e.(*ZoneError).Zone)
+				}
+			}
+			if !reflect.DeepEqual(failed, tc.failed) {
+				t.Errorf("got failed zones %v, want %v", failed, tc.failed)
+			}
+
+			var want []target.Target
+			for _, spec := range tc.want {
+				want = append(want, parseTestTarget(spec))
+			}
+			for i := range got {
+				got[i].Source = ""
+			}
+			assertTargets(t, got, want)
+		})
+	}
+}
+
+// parseTestTarget converts "domain[:port][/protocol][@address]" into
+// target. Port defaults to DefaultPort.
+func parseTestTarget(spec string) target.Target {
+	t := target.Target{Port: DefaultPort}
+	if idx := strings.IndexByte(spec, '@'); idx >= 0 {
+		spec, t.Address = spec[:idx], spec[idx+1:]
+	}
+	if idx := strings.IndexByte(spec, '/'); idx >= 0 {
+		spec, t.Protocol = spec[:idx], target.Protocol(spec[idx+1:])
+	}
+	if idx := strings.IndexByte(spec, ':'); idx >= 0 {
+		spec, t.Port = spec[:idx], spec[idx+1:]
+	}
+	t.Domain = spec
+	return t
+}
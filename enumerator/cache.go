@@ -0,0 +1,236 @@
+package enumerator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/mysteriumnetwork/everssl/target"
+)
+
+// DefaultCacheMaxAge is the age after which cached targets are no longer
+// used as fallback
+const DefaultCacheMaxAge = 24 * time.Hour
+
+type cacheEntry struct {
+	Time    time.Time       `json:"time"`
+	IPv6    bool            `json:"ipv6"`
+	Targets []target.Target `json:"targets"`
+}
+
+type cacheFile struct {
+	Zones map[string]cacheEntry `json:"zones"`
+}
+
+// CacheFallbackError is returned when live enumeration of the zone failed
+// and targets were taken from cache instead. It's a warning rather than
+// failure, since targets of the zone are still checked.
+type CacheFallbackError struct {
+	Zone string
+	Time time.Time
+	Err  error
+}
+
+func (e *CacheFallbackError) Error() string {
+	return fmt.Sprintf("%v; using targets of zone %s cached at %s", e.Err, e.Zone, e.Time.Format(time.RFC3339))
+}
+
+func (e *CacheFallbackError) Unwrap() error {
+	return e.Err
+}
+
+// CachingEnumerator persists results of successful enumerations to a local
+// file and falls back to them if live enumeration fails.
+type CachingEnumerator struct {
+	enumerator Enumerator
+	path       string
+	maxAge     time.Duration
+	mux        sync.Mutex
+}
+
+func NewCachingEnumerator(enumerator Enumerator, path string) *CachingEnumerator {
+	return &CachingEnumerator{
+		enumerator: enumerator,
+		path:       path,
+		maxAge:     DefaultCacheMaxAge,
+	}
+}
+
+// SetMaxAge sets maximal age of cached targets used as fallback. Zero
+// means no limit.
+func (e *CachingEnumerator) SetMaxAge(maxAge time.Duration) *CachingEnumerator {
+	e.maxAge = maxAge
+	return e
+}
+
+func (e *CachingEnumerator) Enumerate(ctx context.Context, zone string, ipv6 bool) ([]target.Target, error) {
+	targets, err := e.enumerator.Enumerate(ctx, zone, ipv6)
+
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	cache, cacheErr := e.load()
+	if cacheErr != nil {
+		log.Printf("Unable to load enumeration cache %s: %v", e.path, cacheErr)
+		cache = &cacheFile{}
+	}
+	if cache.Zones == nil {
+		cache.Zones = make(map[string]cacheEntry)
+	}
+
+	if isComplete(err) {
+		cache.Zones[zone] = cacheEntry{
+			Time:    time.Now(),
+			IPv6:    ipv6,
+			Targets: targets,
+		}
+		if cacheErr := e.save(cache); cacheErr != nil {
+			log.Printf("Unable to save enumeration cache %s: %v", e.path, cacheErr)
+		}
+		return targets, err
+	}
+
+	entry, ok := cache.Zones[zone]
+	if !ok || entry.IPv6 != ipv6 {
+		return targets, err
+	}
+	if e.maxAge > 0 && time.Since(entry.Time) > e.maxAge {
+		log.Printf("Cached targets of zone %s are stale (cached at %s), not using them",
+			zone, entry.Time.Format(time.RFC3339))
+		return targets, err
+	}
+
+	// problems with particular targets and files are kept as is, failed
+	// zones are covered by their cached targets, any other failure is
+	// covered by all cached targets
+	var (
+		zoneErrs []*ZoneError
+		failures []error
+		problems []error
+	)
+	errs := []error{err}
+	if merr, ok := err.(*multierror.Error); ok {
+		errs = merr.Errors
+	}
+	for _, subErr := range errs {
+		switch e := subErr.(type) {
+		case *TargetError, *FileError:
+			problems = append(problems, e)
+		case *ZoneError:
+			zoneErrs = append(zoneErrs, e)
+		default:
+			failures = append(failures, e)
+		}
+	}
+
+	var resultErr error
+	if len(failures) > 0 {
+		fallbackErr := &CacheFallbackError{
+			Zone: zone,
+			Time: entry.Time,
+			Err:  failures[0],
+		}
+		if len(failures)+len(zoneErrs) > 1 {
+			for _, zoneErr := range zoneErrs {
+				failures = append(failures, zoneErr)
+			}
+			fallbackErr.Err = multierror.Append(nil, failures...)
+		}
+		resultErr = multierror.Append(resultErr, fallbackErr)
+		targets = append(targets, entry.Targets...)
+		log.Printf("Enumeration of zone %s failed, using %d targets cached at %s",
+			zone, len(entry.Targets), entry.Time.Format(time.RFC3339))
+	} else {
+		for _, zoneErr := range zoneErrs {
+			var cached []target.Target
+			for _, t := range entry.Targets {
+				if inZone(t.Domain, zoneErr.Zone) {
+					cached = append(cached, t)
+				}
+			}
+			resultErr = multierror.Append(resultErr, &CacheFallbackError{
+				Zone: zoneErr.Zone,
+				Time: entry.Time,
+				Err:  zoneErr.Err,
+			})
+			targets = append(targets, cached...)
+			log.Printf("Enumeration of zone %s failed, using %d targets cached at %s",
+				zoneErr.Zone, len(cached), entry.Time.Format(time.RFC3339))
+		}
+	}
+
+	if len(problems) > 0 {
+		resultErr = multierror.Append(resultErr, problems...)
+	}
+	return target.Merge(targets), resultErr
+}
+
+// isComplete checks if enumeration succeeded for all zones, so its result
+// may replace cached one
+func isComplete(err error) bool {
+	if err == nil {
+		return true
+	}
+
+	errs := []error{err}
+	if merr, ok := err.(*multierror.Error); ok {
+		errs = merr.Errors
+	}
+	for _, e := range errs {
+		switch e.(type) {
+		case *TargetError, *FileError:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func (e *CachingEnumerator) load() (*cacheFile, error) {
+	data, err := os.ReadFile(e.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &cacheFile{}, nil
+		}
+		return nil, err
+	}
+
+	var cache cacheFile
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, fmt.Errorf("cache decoding failed: %w", err)
+	}
+	return &cache, nil
+}
+
+// save writes cache to temporary file first, so interrupted run doesn't
+// leave corrupted cache behind
+func (e *CachingEnumerator) save(cache *cacheFile) error {
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return fmt.Errorf("cache encoding failed: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(e.path), filepath.Base(e.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), e.path)
+}
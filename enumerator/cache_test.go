@@ -0,0 +1,132 @@
+package enumerator
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/mysteriumnetwork/everssl/target"
+)
+
+func TestCachingEnumerator(t *testing.T) {
+	cachedTargets := []target.Target{
+		{Domain: "www.a.example", Port: DefaultPort},
+		{Domain: "www.b.example", Port: DefaultPort},
+		{Domain: "*.b.example", Port: DefaultPort},
+	}
+	liveTarget := target.Target{Domain: "new.a.example", Port: DefaultPort}
+	zoneErr := func(zone string) error {
+		return &ZoneError{Zone: zone, Err: errors.New("forbidden")}
+	}
+	targetErr := &TargetError{Target: liveTarget, Err: errors.New("expired")}
+
+	tests := []struct {
+		name      string
+		ipv6      bool
+		maxAge    time.Duration
+		targets   []target.Target
+		err       error
+		want      []target.Target
+		fallbacks []string
+		others    int
+	}{
+		{
+			name:    "success replaces cache",
+			targets: []target.Target{liveTarget},
+			err:     targetErr,
+			want:    []target.Target{liveTarget},
+			others:  1,
+		},
+		{
+			name:      "failure is covered by all cached targets",
+			err:       errors.New("unauthorized"),
+			want:      cachedTargets,
+			fallbacks: []string{AllZones},
+		},
+		{
+			name:      "failed zones are covered by their cached targets",
+			targets:   []target.Target{liveTarget},
+			err:       multierror.Append(nil, zoneErr("b.example"), targetErr, zoneErr("c.example")),
+			want:      append([]target.Target{liveTarget}, cachedTargets[1:]...),
+			fallbacks: []string{"b.example", "c.example"},
+			others:    1,
+		},
+		{
+			name:   "stale cache",
+			maxAge: time.Nanosecond,
+			err:    errors.New("unauthorized"),
+			others: 1,
+		},
+		{
+			name:   "cache of other IPv6 setting",
+			ipv6:   true,
+			err:    errors.New("unauthorized"),
+			others: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stub := &stubEnumerator{targets: cachedTargets}
+			e := NewCachingEnumerator(stub, filepath.Join(t.TempDir(), "cache.json"))
+			if _, err := e.Enumerate(context.Background(), AllZones, false); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.maxAge > 0 {
+				e.SetMaxAge(tc.maxAge)
+				time.Sleep(time.Millisecond)
+			}
+
+			stub.targets, stub.err = tc.targets, tc.err
+			got, err := e.Enumerate(context.Background(), AllZones, tc.ipv6)
+			assertTargets(t, got, tc.want)
+
+			var (
+				fallbacks []string
+				others    int
+			)
+			errs := []error{err}
+			if merr, ok := err.(*multierror.Error); ok {
+				errs = merr.Errors
+			}
+			for _, e := range errs {
+				if fallbackErr, ok := e.(*CacheFallbackError); ok {
+					fallbacks = append(fallbacks, fallbackErr.Zone)
+				} else if e != nil {
+					others++
+				}
+			}
+			sort.Strings(fallbacks)
+			if len(fallbacks) != len(tc.fallbacks) || others != tc.others {
+				t.Fatalf("got fallbacks %v and %d other errors, want %v and %d (%v)",
+					fallbacks, others, tc.fallbacks, tc.others, err)
+			}
+			for i := range fallbacks {
+				if fallbacks[i] != tc.fallbacks[i] {
+					t.Errorf("got fallbacks %v, want %v", fallbacks, tc.fallbacks)
+				}
+			}
+			if len(tc.fallbacks) > 0 && IsFailure(err) {
+				t.Errorf("fallback is treated as failure: %v", err)
+			}
+		})
+	}
+}
+
+func TestCachingEnumeratorKeepsCacheOnPartialFailure(t *testing.T) {
+	stub := &stubEnumerator{targets: []target.Target{{Domain: "www.b.example", Port: DefaultPort}}}
+	e := NewCachingEnumerator(stub, filepath.Join(t.TempDir(), "cache.json"))
+	e.Enumerate(context.Background(), AllZones, false)
+
+	// partial result must not replace complete one
+	stub.targets, stub.err = nil, &ZoneError{Zone: "b.example", Err: errors.New("forbidden")}
+	e.Enumerate(context.Background(), AllZones, false)
+	stub.err = errors.New("unauthorized")
+	got, _ := e.Enumerate(context.Background(), AllZones, false)
+	assertTargets(t, got, []target.Target{{Domain: "www.b.example", Port: DefaultPort}})
+}
@@ -0,0 +1,123 @@
+package enumerator
+
+// Enumerator which lists local certificate files and Kubernetes TLS
+// secret manifests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/mysteriumnetwork/everssl/certfile"
+	"github.com/mysteriumnetwork/everssl/target"
+)
+
+// CertFileEnumerator emits file targets for certificates stored locally.
+// Certificates are matched to zones by names of their leaf certificates.
+// Files which can't be read or parsed are reported as FileErrors.
+type CertFileEnumerator struct {
+	paths          []string
+	pkcs12Password string
+}
+
+// NewCertFileEnumerator creates enumerator for certificate files and
+// directories which are searched recursively
+func NewCertFileEnumerator(paths ...string) *CertFileEnumerator {
+	return &CertFileEnumerator{
+		paths: paths,
+	}
+}
+
+// SetPKCS12Password sets password of PKCS#12 certificate files
+func (e *CertFileEnumerator) SetPKCS12Password(password string) *CertFileEnumerator {
+	e.pkcs12Password = password
+	return e
+}
+
+func (e *CertFileEnumerator) Enumerate(ctx context.Context, zone string, ipv6 bool) ([]target.Target, error) {
+	var (
+		res  []target.Target
+		errs error
+	)
+	for _, root := range e.paths {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				errs = multierror.Append(errs, &FileError{Path: path, Err: err})
+				return nil
+			}
+			if d.IsDir() {
+				return nil
+			}
+			// files found in directories are filtered by extension,
+			// explicitly passed ones are always loaded
+			explicit := path == root
+			if !explicit && !certfile.IsCertFile(path) {
+				return nil
+			}
+
+			bundles, err := certfile.LoadAll(path, e.pkcs12Password)
+			if err != nil {
+				if !explicit && errors.Is(err, certfile.ErrNoCertificates) {
+					return nil
+				}
+				errs = multierror.Append(errs, &FileError{Path: path, Err: err})
+				return nil
+			}
+
+			for _, b := range bundles {
+				domain, ok := certDomain(b, zone)
+				if !ok {
+					continue
+				}
+				source := fmt.Sprintf("certificate file %s", path)
+				if b.Name != "" {
+					source = fmt.Sprintf("Kubernetes secret %s in %s", b.Name, path)
+				}
+				res = append(res, target.Target{
+					Domain:   domain,
+					Address:  b.Address(path),
+					Protocol: target.ProtocolFile,
+					Source:   source,
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("unable to walk %q: %w", root, err))
+		}
+	}
+
+	return target.Merge(res), errs
+}
+
+// certDomain picks leaf certificate name within the zone, preferring
+// non-wildcard names. Certificates without names belong to "__all__" only.
+func certDomain(b *certfile.Bundle, zone string) (string, bool) {
+	leaf := b.Leaf()
+	names := leaf.DNSNames
+	if len(names) == 0 && leaf.Subject.CommonName != "" {
+		names = []string{leaf.Subject.CommonName}
+	}
+	if len(names) == 0 {
+		return "", zone == AllZones
+	}
+
+	wildcard := ""
+	for _, name := range names {
+		if !inZone(name, zone) {
+			continue
+		}
+		if !strings.HasPrefix(name, "*") {
+			return name, true
+		}
+		if wildcard == "" {
+			wildcard = name
+		}
+	}
+	return wildcard, wildcard != ""
+}
@@ -0,0 +1,47 @@
+package enumerator
+
+import (
+	"context"
+	"encoding/pem"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/mysteriumnetwork/everssl/target"
+)
+
+func TestCertFileEnumerator(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"www.pem":     string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: testCertDER(t, "www.example.com")})),
+		"corrupt.pem": "-----BEGIN CERTIFICATE-----\nAAAA\n-----END CERTIFICATE-----\n",
+		"README.txt":  "not a certificate\n",
+	})
+	missing := filepath.Join(dir, "missing.crt")
+
+	got, err := NewCertFileEnumerator(dir, missing).Enumerate(context.Background(), AllZones, false)
+	assertTargets(t, got, []target.Target{{
+		Domain:   "www.example.com",
+		Address:  filepath.Join(dir, "www.pem"),
+		Protocol: target.ProtocolFile,
+		Source:   "certificate file " + filepath.Join(dir, "www.pem"),
+	}})
+
+	if IsFailure(err) {
+		t.Fatalf("bad files are treated as failure: %v", err)
+	}
+	var paths []string
+	if merr, ok := err.(*multierror.Error); ok {
+		for _, e := range merr.Errors {
+			if fileErr, ok := e.(*FileError); ok {
+				paths = append(paths, fileErr.Path)
+			}
+		}
+	}
+	sort.Strings(paths)
+	want := []string{filepath.Join(dir, "corrupt.pem"), missing}
+	if len(paths) != len(want) || paths[0] != want[0] || paths[1] != want[1] {
+		t.Errorf("got bad files %v (%v), want %v", paths, err, want)
+	}
+}
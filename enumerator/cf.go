@@ -3,19 +3,23 @@ package enumerator
 import (
 	"context"
 	"fmt"
+	"log"
 	"net"
 	"sync"
 
 	"github.com/cloudflare/cloudflare-go"
+	"github.com/hashicorp/go-multierror"
 
 	"github.com/mysteriumnetwork/everssl/enumerator/cfhelper"
 	"github.com/mysteriumnetwork/everssl/target"
 )
 
 const (
-	MaxRetries        = 3
-	MinRetryDelaySecs = 1
-	MaxRetryDelaySecs = 10
+	MaxRetries           = 3
+	MinRetryDelaySecs    = 1
+	MaxRetryDelaySecs    = 10
+	DefaultPort          = "443"
+	DefaultCFConcurrency = 8
 )
 
 var (
@@ -24,153 +28,227 @@ var (
 )
 
 type CFEnumerator struct {
-	api           *cloudflare.API
-	poolAddresses map[string][]string
-	paMux         sync.RWMutex
+	api         *cloudflare.API
+	pools       map[string]cloudflare.LoadBalancerPool
+	poolsMux    sync.RWMutex
+	scanMX      bool
+	proxyPorts  []string
+	concurrency int
+	zoneFilter  cfhelper.ZoneFilter
+
+	scanCustomHostnames bool
+	scanSpectrum        bool
+	resolver            Resolver
 }
 
-func NewCFEnumerator(apiToken string) (*CFEnumerator, error) {
-	api, err := cloudflare.NewWithAPIToken(apiToken,
-		cloudflare.UsingRetryPolicy(MaxRetries, MinRetryDelaySecs, MaxRetryDelaySecs))
+// NewCFEnumerator creates Cloudflare enumerator. Options are passed to
+// Cloudflare API client after default ones.
+func NewCFEnumerator(apiToken string, opts ...cloudflare.Option) (*CFEnumerator, error) {
+	opts = append([]cloudflare.Option{
+		cloudflare.UsingRetryPolicy(MaxRetries, MinRetryDelaySecs, MaxRetryDelaySecs),
+	}, opts...)
+	api, err := cloudflare.NewWithAPIToken(apiToken, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("can't instantiate Cloudflare API client: %w", err)
 	}
 
 	return &CFEnumerator{
-		api:           api,
-		poolAddresses: make(map[string][]string),
+		api:         api,
+		pools:       make(map[string]cloudflare.LoadBalancerPool),
+		proxyPorts:  []string{DefaultPort},
+		concurrency: DefaultCFConcurrency,
 	}, nil
 }
 
+// SetScanMX enables enumeration of mail exchangers as SMTP targets
+func (e *CFEnumerator) SetScanMX(scanMX bool) *CFEnumerator {
+	e.scanMX = scanMX
+	return e
+}
+
+// SetProxyPorts sets ports checked on Cloudflare edge for proxied hostnames.
+// Cloudflare proxies HTTPS on 443, 2053, 2083, 2087, 2096 and 8443.
+func (e *CFEnumerator) SetProxyPorts(ports []string) *CFEnumerator {
+	e.proxyPorts = ports
+	return e
+}
+
+// SetResolver enables resolution of hostname origins of CNAME records and
+// load balancer pools into targets for each IP address. Nil resolver
+// disables resolution.
+func (e *CFEnumerator) SetResolver(resolver Resolver) *CFEnumerator {
+	e.resolver = resolver
+	return e
+}
+
+// SetConcurrency limits number of zones enumerated simultaneously
+func (e *CFEnumerator) SetConcurrency(concurrency int) *CFEnumerator {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	e.concurrency = concurrency
+	return e
+}
+
+// SetZoneFilter restricts enumerated zones
+func (e *CFEnumerator) SetZoneFilter(filter cfhelper.ZoneFilter) *CFEnumerator {
+	e.zoneFilter = filter
+	return e
+}
+
 func (e *CFEnumerator) Enumerate(ctx context.Context, zone string, ipv6 bool) ([]target.Target, error) {
-	if zone == "__all__" {
+	if zone == AllZones {
 		return e.enumerateAllDomains(ctx, ipv6)
 	}
 
-	zoneID, accountID, err := cfhelper.ZoneIDByName(ctx, e.api, zone)
+	cfZone, err := cfhelper.ZoneByName(ctx, e.api, zone)
+	if err != nil {
+		return nil, fmt.Errorf("ZoneByName failed: %w", err)
+	}
+
+	if !e.zoneFilter.Match(cfZone) {
+		log.Printf("Cloudflare zone %s is skipped by zone filter", zone)
+		return nil, nil
+	}
+
+	targets, problems, err := e.enumerateDomain(ctx, cfZone.Account.ID, cfZone.ID, zone, ipv6)
 	if err != nil {
-		return nil, fmt.Errorf("ZoneIDByName failed: %w", err)
+		return nil, err
 	}
 
-	return e.enumerateDomain(ctx, accountID, zoneID, ipv6)
+	var problemsErr error
+	if len(problems) > 0 {
+		problemsErr = multierror.Append(nil, problems...)
+	}
+	return targets, problemsErr
 }
 
-func (e *CFEnumerator) resolveLBPool(ctx context.Context, accountID, poolID string) ([]string, error) {
-	e.paMux.RLock()
-	addresses, ok := e.poolAddresses[poolID]
-	e.paMux.RUnlock()
+func (e *CFEnumerator) resolveLBPool(ctx context.Context, accountID, poolID string) (cloudflare.LoadBalancerPool, error) {
+	e.poolsMux.RLock()
+	pool, ok := e.pools[poolID]
+	e.poolsMux.RUnlock()
 	if ok {
-		return addresses, nil
+		return pool, nil
 	}
 
 	pool, err := e.api.GetLoadBalancerPool(ctx, cloudflare.AccountIdentifier(accountID), poolID)
 	if err != nil {
-		return nil, fmt.Errorf("GetLoadBalancerPool failed: %w", err)
-	}
-	for _, origin := range pool.Origins {
-		addresses = append(addresses, origin.Address)
+		return pool, fmt.Errorf("GetLoadBalancerPool failed: %w", err)
 	}
 
-	e.paMux.Lock()
-	defer e.paMux.Unlock()
-	e.poolAddresses[poolID] = addresses
+	e.poolsMux.Lock()
+	defer e.poolsMux.Unlock()
+	e.pools[poolID] = pool
 
-	return addresses, nil
+	return pool, nil
 }
 
 func (e *CFEnumerator) enumerateAllDomains(ctx context.Context, ipv6 bool) ([]target.Target, error) {
-	lzr, err := e.api.ListZonesContext(ctx)
+	allZones, err := cfhelper.ListZones(ctx, e.api, e.zoneFilter.Params())
 	if err != nil {
 		return nil, fmt.Errorf("ListZones failed: %w", err)
 	}
 
-	var result []target.Target
-	for _, zone := range lzr.Result {
-		zoneTargets, err := e.enumerateDomain(ctx, zone.Account.ID, zone.ID, ipv6)
-		if err != nil {
-			return nil, fmt.Errorf("enumerateDomain %q (zoneID=%q accountID=%q) failed: %w", zone.Name, zone.Account.ID, zone.ID, err)
+	var zones []cloudflare.Zone
+	for _, zone := range allZones {
+		if e.zoneFilter.Match(zone) {
+			zones = append(zones, zone)
 		}
-
-		result = append(result, zoneTargets...)
+	}
+	if len(zones) < len(allZones) {
+		log.Printf("Cloudflare zones skipped by zone filter: %d/%d", len(allZones)-len(zones), len(allZones))
 	}
 
-	return result, nil
-}
+	var (
+		result    []target.Target
+		resultErr error
+		resMux    sync.Mutex
+		wg        sync.WaitGroup
+		done      int
+	)
+	sem := make(chan struct{}, e.concurrency)
 
-func isFakeOriginAddress(ip string) bool {
-	parsedIP := net.ParseIP(ip)
-	if parsedIP == nil {
-		return false
-	}
-	if parsedIP.Equal(CFWorkersBackendAddress) || parsedIP.Equal(PlaceholderAddress) {
-		return true
+	wg.Add(len(zones))
+	for _, zone := range zones {
+		go func(zone cloudflare.Zone) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			zoneTargets, problems, err := e.enumerateDomain(ctx, zone.Account.ID, zone.ID, zone.Name, ipv6)
+
+			resMux.Lock()
+			defer resMux.Unlock()
+			done++
+			if err != nil {
+				resultErr = multierror.Append(resultErr, &ZoneError{
+					Zone: zone.Name,
+					Err:  fmt.Errorf("enumerateDomain (zoneID=%q accountID=%q) failed: %w", zone.ID, zone.Account.ID, err),
+				})
+				log.Printf("Cloudflare zones enumerated: %d/%d (zone %s failed)", done, len(zones), zone.Name)
+				return
+			}
+			result = append(result, zoneTargets...)
+			if len(problems) > 0 {
+				resultErr = multierror.Append(resultErr, problems...)
+			}
+			log.Printf("Cloudflare zones enumerated: %d/%d (zone %s: %d targets)", done, len(zones), zone.Name, len(zoneTargets))
+		}(zone)
 	}
-	return false
+	wg.Wait()
+
+	return result, resultErr
 }
 
-func (e *CFEnumerator) enumerateDomain(ctx context.Context, accountID, zoneID string, ipv6 bool) ([]target.Target, error) {
+func (e *CFEnumerator) enumerateDomain(ctx context.Context, accountID, zoneID, zoneName string, ipv6 bool) ([]target.Target, []error, error) {
 	targets := make(map[target.Target]struct{})
+	source := fmt.Sprintf("Cloudflare zone %s", zoneName)
 
-	unfilteredRecs, _, err := e.api.ListDNSRecords(ctx, cloudflare.ZoneIdentifier(zoneID), cloudflare.ListDNSRecordsParams{})
+	unfilteredRecs, err := cfhelper.ListDNSRecords(ctx, e.api, zoneID)
 	if err != nil {
-		return nil, fmt.Errorf("ListDNSRecords failed: %w", err)
+		return nil, nil, fmt.Errorf("ListDNSRecords failed: %w", err)
 	}
 
-	var recs []cloudflare.DNSRecord
+	recs := make([]dnsRecord, 0, len(unfilteredRecs))
 	for _, rec := range unfilteredRecs {
-		switch rec.Type {
-		case "A", "CNAME", "NS":
-			recs = append(recs, rec)
-		case "AAAA":
-			if ipv6 {
-				recs = append(recs, rec)
-			}
-		}
+		recs = append(recs, dnsRecord{
+			Name:    rec.Name,
+			Type:    rec.Type,
+			Content: rec.Content,
+			Proxied: rec.Proxied != nil && *rec.Proxied,
+		})
 	}
+	addRecordTargets(targets, recs, ipv6, e.scanMX, e.proxyPorts, source)
 
-	for _, record := range recs {
-		checkOrigin := true
-		checkProxy := true
-		switch record.Type {
-		case "A", "AAAA":
-			checkOrigin = !(record.Proxied != nil && *record.Proxied && isFakeOriginAddress(record.Content))
-			checkProxy = record.Proxied != nil && *record.Proxied
-		case "CNAME":
-			checkProxy = record.Proxied != nil && *record.Proxied
-			checkOrigin = true
-		}
-
-		// Add target for the domain name directly to origin server
-		if checkOrigin {
-			targets[target.Target{
-				Domain:  record.Name,
-				Address: record.Content,
-			}] = struct{}{}
-		}
-
-		// Add target for the domain name via CF
-		if checkProxy {
-			targets[target.Target{
-				Domain:  record.Name,
-				Address: "",
-			}] = struct{}{}
-		}
-	}
-
-	lbs, err := e.api.ListLoadBalancers(ctx,
-		cloudflare.ZoneIdentifier(zoneID),
-		cloudflare.ListLoadBalancerParams{},
-	)
+	// load balancer failures don't make DNS record targets unusable, e.g.
+	// when token lacks load balancer permissions
+	var problems []error
+	lbs, err := cfhelper.ListLoadBalancers(ctx, e.api, zoneID)
 	if err != nil {
-		return nil, fmt.Errorf("ListLoadBalancers failed: %w", err)
+		problems = append(problems, &ZoneError{
+			Zone: zoneName,
+			Err:  fmt.Errorf("ListLoadBalancers failed: %w", err),
+		})
 	}
 
+	failedPools := make(map[string]struct{})
+
 	for _, lb := range lbs {
+		if lb.Enabled != nil && !*lb.Enabled {
+			continue
+		}
 		if lb.Proxied {
-			targets[target.Target{
-				Domain:  lb.Name,
-				Address: "",
-			}] = struct{}{}
+			for _, port := range e.proxyPorts {
+				targets[target.Target{
+					Domain:  lb.Name,
+					Address: "",
+					Port:    port,
+					Source:  source,
+					LoadBalancer: target.LoadBalancer{
+						Name: lb.Name,
+					},
+				}] = struct{}{}
+			}
 		}
 		pools := lb.DefaultPools
 		pools = append(pools, lb.FallbackPool)
@@ -184,27 +262,74 @@ func (e *CFEnumerator) enumerateDomain(ctx context.Context, accountID, zoneID st
 			pools = append(pools, pool...)
 		}
 
-		for _, pool := range pools {
-			addresses, err := e.resolveLBPool(ctx, accountID, pool)
+		for _, poolID := range pools {
+			if _, ok := failedPools[poolID]; ok {
+				continue
+			}
+			pool, err := e.resolveLBPool(ctx, accountID, poolID)
 			if err != nil {
-				return nil, fmt.Errorf("resolveLBPool failed: %w", err)
+				failedPools[poolID] = struct{}{}
+				problems = append(problems, &ZoneError{
+					Zone: zoneName,
+					Err:  fmt.Errorf("resolveLBPool (poolID=%q) failed: %w", poolID, err),
+				})
+				continue
 			}
 
-			for _, addr := range addresses {
+			// drained backends are disabled intentionally
+			if !pool.Enabled {
+				continue
+			}
+			for _, origin := range pool.Origins {
+				if !origin.Enabled {
+					continue
+				}
 				targets[target.Target{
 					Domain:  lb.Name,
-					Address: addr,
+					Address: origin.Address,
+					Port:    DefaultPort,
+					Source:  source,
+					LoadBalancer: target.LoadBalancer{
+						Name:   lb.Name,
+						Pool:   pool.Name,
+						Origin: origin.Name,
+					},
 				}] = struct{}{}
 			}
 		}
 
 	}
 
+	if e.scanCustomHostnames {
+		hostTargets, hostProblems, err := e.enumerateCustomHostnames(ctx, zoneID, zoneName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("enumerateCustomHostnames failed: %w", err)
+		}
+		for _, t := range hostTargets {
+			targets[t] = struct{}{}
+		}
+		problems = append(problems, hostProblems...)
+	}
+
+	if e.scanSpectrum {
+		appTargets, err := e.enumerateSpectrum(ctx, zoneID, zoneName, ipv6)
+		if err != nil {
+			return nil, nil, fmt.Errorf("enumerateSpectrum failed: %w", err)
+		}
+		for _, t := range appTargets {
+			targets[t] = struct{}{}
+		}
+	}
+
 	res := make([]target.Target, 0, len(targets))
 
 	for k, _ := range targets {
 		res = append(res, k)
 	}
 
-	return res, nil
+	if e.resolver != nil {
+		res = resolveOrigins(ctx, e.resolver, res, ipv6)
+	}
+
+	return res, problems, nil
 }
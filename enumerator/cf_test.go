@@ -0,0 +1,387 @@
+package enumerator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/cloudflare/cloudflare-go"
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/mysteriumnetwork/everssl/enumerator/cfhelper"
+	"github.com/mysteriumnetwork/everssl/target"
+)
+
+type fakeCFZone struct {
+	ID      string
+	Name    string
+	Status  string
+	Records []map[string]interface{}
+	// CustomHostnames are Cloudflare for SaaS custom hostnames
+	CustomHostnames []map[string]interface{}
+	SpectrumApps    []map[string]interface{}
+	// Broken zone fails DNS records listing
+	Broken bool
+	// BrokenLB zone fails load balancers listing
+	BrokenLB bool
+}
+
+func writeCFResult(w http.ResponseWriter, result interface{}) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"errors":   []interface{}{},
+		"messages": []interface{}{},
+		"result":   result,
+		"result_info": map[string]interface{}{
+			"page":        1,
+			"per_page":    100,
+			"total_pages": 1,
+		},
+	})
+}
+
+func writeCFForbidden(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusForbidden)
+	w.Write([]byte(`{"success":false,"errors":[{"code":10000,"message":"Authentication error"}],"messages":[],"result":null}`))
+}
+
+// startFakeCF serves zones, their DNS records, custom hostnames, Spectrum
+// applications and empty load balancer listings. Zones are filtered by name and status query parameters.
+// Listings of broken zones are refused as by token without permissions.
+func startFakeCF(t *testing.T, zones []fakeCFZone) *CFEnumerator {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/zones", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		res := []map[string]interface{}{}
+		if q.Get("page") == "1" || q.Get("page") == "" {
+			for _, z := range zones {
+				if name := q.Get("name"); name != "" && name != z.Name {
+					continue
+				}
+				if status := q.Get("status"); status != "" && status != z.Status {
+					continue
+				}
+				res = append(res, map[string]interface{}{
+					"id":      z.ID,
+					"name":    z.Name,
+					"status":  z.Status,
+					"account": map[string]string{"id": "acc1"},
+				})
+			}
+		}
+		writeCFResult(w, res)
+	})
+	mux.HandleFunc("/zones/", func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.SplitN(strings.Trim(r.URL.Path, "/"), "/", 3)
+		if len(parts) != 3 {
+			http.NotFound(w, r)
+			return
+		}
+		for _, z := range zones {
+			if z.ID != parts[1] {
+				continue
+			}
+			switch parts[2] {
+			case "dns_records":
+				if z.Broken {
+					writeCFForbidden(w)
+					return
+				}
+				writeCFResult(w, z.Records)
+			case "load_balancers":
+				if z.BrokenLB {
+					writeCFForbidden(w)
+					return
+				}
+				writeCFResult(w, []interface{}{})
+			case "custom_hostnames":
+				writeCFResult(w, z.CustomHostnames)
+			case "spectrum/apps":
+				writeCFResult(w, z.SpectrumApps)
+			default:
+				http.NotFound(w, r)
+			}
+			return
+		}
+		http.NotFound(w, r)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	e, err := NewCFEnumerator("token", cloudflare.BaseURL(srv.URL), cloudflare.UsingRateLimit(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestCFEnumerator(t *testing.T) {
+	record := func(name, typ, content string, proxied bool) map[string]interface{} {
+		return map[string]interface{}{
+			"name": name, "type": typ, "content": content, "proxied": proxied,
+		}
+	}
+	zones := []fakeCFZone{
+		{
+			ID:     "z1",
+			Name:   "good.example",
+			Status: "active",
+			Records: []map[string]interface{}{
+				record("www.good.example", "A", "192.0.2.10", false),
+				record("cdn.good.example", "A", "192.0.2.1", true),
+			},
+		},
+		{
+			ID:     "z2",
+			Name:   "broken.example",
+			Status: "active",
+			Broken: true,
+		},
+		{
+			ID:     "z3",
+			Name:   "pending.example",
+			Status: "pending",
+			Records: []map[string]interface{}{
+				record("www.pending.example", "A", "192.0.2.30", false),
+			},
+		},
+		{
+			ID:       "z4",
+			Name:     "nolb.example",
+			Status:   "pending",
+			BrokenLB: true,
+			Records: []map[string]interface{}{
+				record("www.nolb.example", "A", "192.0.2.40", false),
+			},
+		},
+	}
+	goodTargets := []target.Target{
+		{Domain: "www.good.example", Address: "192.0.2.10", Port: DefaultPort, Source: "Cloudflare zone good.example"},
+		{Domain: "cdn.good.example", Port: DefaultPort, Source: "Cloudflare zone good.example"},
+	}
+	pendingTarget := target.Target{
+		Domain: "www.pending.example", Address: "192.0.2.30", Port: DefaultPort, Source: "Cloudflare zone pending.example",
+	}
+	noLBTarget := target.Target{
+		Domain: "www.nolb.example", Address: "192.0.2.40", Port: DefaultPort, Source: "Cloudflare zone nolb.example",
+	}
+
+	tests := []struct {
+		name       string
+		zone       string
+		filter     cfhelper.ZoneFilter
+		want       []target.Target
+		wantZones  []string
+		wantFailed bool
+	}{
+		{
+			name:      "broken zone is reported separately",
+			zone:      AllZones,
+			want:      append([]target.Target{pendingTarget, noLBTarget}, goodTargets...),
+			wantZones: []string{"broken.example", "nolb.example"},
+		},
+		{
+			name:      "zone filter",
+			zone:      AllZones,
+			filter:    cfhelper.ZoneFilter{Statuses: []string{"pending"}},
+			want:      []target.Target{pendingTarget, noLBTarget},
+			wantZones: []string{"nolb.example"},
+		},
+		{
+			name:      "load balancers listing failure keeps DNS targets",
+			zone:      "nolb.example",
+			want:      []target.Target{noLBTarget},
+			wantZones: []string{"nolb.example"},
+		},
+		{
+			name: "single zone",
+			zone: "good.example",
+			want: goodTargets,
+		},
+		{
+			name:   "single zone skipped by filter",
+			zone:   "good.example",
+			filter: cfhelper.ZoneFilter{NameGlobs: []string{"*.org"}},
+		},
+		{
+			name:       "single broken zone",
+			zone:       "broken.example",
+			wantFailed: true,
+		},
+		{
+			name:       "unknown zone",
+			zone:       "unknown.example",
+			wantFailed: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e := startFakeCF(t, zones).SetZoneFilter(tc.filter)
+
+			got, err := e.Enumerate(context.Background(), tc.zone, false)
+			if tc.wantFailed {
+				if !IsFailure(err) {
+					t.Fatalf("failure expected, got %v", err)
+				}
+				return
+			}
+			if IsFailure(err) {
+				t.Fatalf("unexpected failure: %v", err)
+			}
+			assertTargets(t, got, tc.want)
+
+			var failedZones []string
+			if merr, ok := err.(*multierror.Error); ok {
+				for _, e := range merr.Errors {
+					var zoneErr *ZoneError
+					if errors.As(e, &zoneErr) {
+						failedZones = append(failedZones, zoneErr.Zone)
+					}
+				}
+			}
+			sort.Strings(failedZones)
+			if strings.Join(failedZones, ",") != strings.Join(tc.wantZones, ",") {
+				t.Errorf("got failed zones %v, want %v", failedZones, tc.wantZones)
+			}
+		})
+	}
+}
+
+func TestCFEnumeratorCustomHostnames(t *testing.T) {
+	zones := []fakeCFZone{
+		{
+			ID:     "z1",
+			Name:   "saas.example",
+			Status: "active",
+			CustomHostnames: []map[string]interface{}{
+				{"hostname": "shop.customer.example", "status": "active", "ssl": map[string]interface{}{"status": "active"}},
+				{"hostname": "old.customer.example", "status": "active", "ssl": map[string]interface{}{"status": "expired"}},
+				{"hostname": "gone.customer.example", "status": "deleted", "ssl": map[string]interface{}{"status": "expired"}},
+			},
+		},
+	}
+	source := "Cloudflare for SaaS zone saas.example"
+
+	tests := []struct {
+		name  string
+		ports []string
+		want  []target.Target
+	}{
+		{
+			name:  "proxy ports",
+			ports: []string{"443", "8443"},
+			want: []target.Target{
+				{Domain: "shop.customer.example", Port: "443", Source: source},
+				{Domain: "shop.customer.example", Port: "8443", Source: source},
+				{Domain: "old.customer.example", Port: "443", Source: source},
+				{Domain: "old.customer.example", Port: "8443", Source: source},
+			},
+		},
+		{
+			name:  "no proxy ports",
+			ports: nil,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e := startFakeCF(t, zones).SetScanCustomHostnames(true).SetProxyPorts(tc.ports)
+
+			got, err := e.Enumerate(context.Background(), "saas.example", false)
+			if IsFailure(err) {
+				t.Fatalf("unexpected failure: %v", err)
+			}
+			assertTargets(t, got, tc.want)
+
+			// problem is reported once regardless of proxy ports
+			merr, ok := err.(*multierror.Error)
+			if !ok || len(merr.Errors) != 1 {
+				t.Fatalf("one problem expected, got %v", err)
+			}
+			var targetErr *TargetError
+			if !errors.As(merr.Errors[0], &targetErr) || targetErr.Target.Domain != "old.customer.example" {
+				t.Errorf("unexpected problem %v", merr.Errors[0])
+			}
+		})
+	}
+}
+
+func TestCFEnumeratorSpectrum(t *testing.T) {
+	app := func(name, tls, protocol string, origin map[string]interface{}) map[string]interface{} {
+		res := map[string]interface{}{
+			"dns":      map[string]interface{}{"type": "CNAME", "name": name},
+			"protocol": protocol,
+			"tls":      tls,
+		}
+		for k, v := range origin {
+			res[k] = v
+		}
+		return res
+	}
+	direct := func(addrs ...string) map[string]interface{} {
+		return map[string]interface{}{"origin_direct": addrs}
+	}
+	zones := []fakeCFZone{
+		{
+			ID:     "z1",
+			Name:   "apps.example",
+			Status: "active",
+			SpectrumApps: []map[string]interface{}{
+				app("pass.apps.example", "off", "tcp/993", direct("tcp://192.0.2.1:10993")),
+				app("flex.apps.example", "flexible", "tcp/8443", direct("tcp://192.0.2.2:8080")),
+				app("full.apps.example", "full", "tcp/1000-1002", direct("tcp://192.0.2.3:2000-2002", "tcp://[2001:db8::3]:3000")),
+				app("strict.apps.example", "strict", "tcp/443", map[string]interface{}{
+					"origin_dns":  map[string]interface{}{"name": "origin.apps.example"},
+					"origin_port": 8443,
+				}),
+				app("udp.apps.example", "off", "udp/53", direct("udp://192.0.2.5:53")),
+			},
+		},
+	}
+
+	tgt := func(name, port, address string) target.Target {
+		return target.Target{
+			Domain:  name,
+			Address: address,
+			Port:    port,
+			Source:  "Cloudflare Spectrum app " + name + " in zone apps.example",
+		}
+	}
+	want := []target.Target{
+		tgt("pass.apps.example", "993", ""),
+		tgt("pass.apps.example", "10993", "192.0.2.1"),
+		tgt("flex.apps.example", "8443", ""),
+		tgt("full.apps.example", "1000", ""),
+		tgt("full.apps.example", "1001", ""),
+		tgt("full.apps.example", "1002", ""),
+		tgt("full.apps.example", "2000", "192.0.2.3"),
+		tgt("full.apps.example", "2001", "192.0.2.3"),
+		tgt("full.apps.example", "2002", "192.0.2.3"),
+		tgt("strict.apps.example", "443", ""),
+		tgt("strict.apps.example", "8443", "origin.apps.example"),
+	}
+
+	// single origin port serves every edge port
+	wantIPv6 := append([]target.Target{tgt("full.apps.example", "3000", "2001:db8::3")}, want...)
+
+	for _, ipv6 := range []bool{false, true} {
+		got, err := startFakeCF(t, zones).SetScanSpectrum(true).Enumerate(context.Background(), "apps.example", ipv6)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if ipv6 {
+			assertTargets(t, got, wantIPv6)
+		} else {
+			assertTargets(t, got, want)
+		}
+	}
+}
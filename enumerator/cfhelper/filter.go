@@ -0,0 +1,79 @@
+package cfhelper
+
+import (
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/cloudflare/cloudflare-go"
+)
+
+// ZoneFilter restricts set of enumerated zones. Empty lists impose no
+// restriction. Zone names are matched against shell-style glob patterns.
+type ZoneFilter struct {
+	AccountIDs []string
+	NameGlobs  []string
+	Statuses   []string
+	// Plans matches either plan name ("Free Website") or legacy plan ID
+	// ("free", "pro", "business", "enterprise")
+	Plans        []string
+	ExcludeGlobs []string
+}
+
+// Match checks if zone passes the filter
+func (f *ZoneFilter) Match(zone cloudflare.Zone) bool {
+	name := strings.ToLower(zone.Name)
+
+	if len(f.AccountIDs) > 0 && !containsFold(f.AccountIDs, zone.Account.ID) {
+		return false
+	}
+
+	if len(f.NameGlobs) > 0 && !matchAnyGlob(f.NameGlobs, name) {
+		return false
+	}
+
+	if len(f.Statuses) > 0 && !containsFold(f.Statuses, zone.Status) {
+		return false
+	}
+
+	if len(f.Plans) > 0 && !containsFold(f.Plans, zone.Plan.Name) && !containsFold(f.Plans, zone.Plan.LegacyID) {
+		return false
+	}
+
+	if matchAnyGlob(f.ExcludeGlobs, name) {
+		return false
+	}
+
+	return true
+}
+
+// Params returns list zones query parameters which narrow the listing
+// on API side where possible
+func (f *ZoneFilter) Params() url.Values {
+	params := url.Values{}
+	if len(f.AccountIDs) == 1 {
+		params.Set("account.id", f.AccountIDs[0])
+	}
+	if len(f.Statuses) == 1 {
+		params.Set("status", strings.ToLower(f.Statuses[0]))
+	}
+	return params
+}
+
+func containsFold(list []string, s string) bool {
+	for _, elem := range list {
+		if strings.EqualFold(elem, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAnyGlob(globs []string, name string) bool {
+	for _, glob := range globs {
+		if ok, _ := path.Match(strings.ToLower(glob), name); ok {
+			return true
+		}
+	}
+	return false
+}
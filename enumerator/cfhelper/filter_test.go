@@ -0,0 +1,89 @@
+package cfhelper
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/cloudflare/cloudflare-go"
+)
+
+func TestZoneFilterParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter ZoneFilter
+		want   url.Values
+	}{
+		{
+			name:   "empty",
+			filter: ZoneFilter{},
+			want:   url.Values{},
+		},
+		{
+			name:   "single account and status",
+			filter: ZoneFilter{AccountIDs: []string{"acc1"}, Statuses: []string{"Active"}},
+			want:   url.Values{"account.id": []string{"acc1"}, "status": []string{"active"}},
+		},
+		{
+			name: "several accounts and statuses are filtered locally",
+			filter: ZoneFilter{
+				AccountIDs: []string{"acc1", "acc2"},
+				Statuses:   []string{"active", "pending"},
+			},
+			want: url.Values{},
+		},
+		{
+			name: "names and plans are filtered locally",
+			filter: ZoneFilter{
+				NameGlobs:    []string{"*.example.com"},
+				Plans:        []string{"free"},
+				ExcludeGlobs: []string{"test.example.com"},
+			},
+			want: url.Values{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.filter.Params(); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestZoneFilterMatch(t *testing.T) {
+	zone := cloudflare.Zone{
+		Name:   "Shop.Example.com",
+		Status: "active",
+	}
+	zone.Account.ID = "acc1"
+	zone.Plan.Name = "Free Website"
+	zone.Plan.LegacyID = "free"
+
+	tests := []struct {
+		name   string
+		filter ZoneFilter
+		want   bool
+	}{
+		{"empty", ZoneFilter{}, true},
+		{"account", ZoneFilter{AccountIDs: []string{"acc2", "acc1"}}, true},
+		{"other account", ZoneFilter{AccountIDs: []string{"acc2"}}, false},
+		{"name glob", ZoneFilter{NameGlobs: []string{"*.EXAMPLE.com"}}, true},
+		{"other name", ZoneFilter{NameGlobs: []string{"*.example.org"}}, false},
+		{"status", ZoneFilter{Statuses: []string{"Active"}}, true},
+		{"other status", ZoneFilter{Statuses: []string{"pending"}}, false},
+		{"plan name", ZoneFilter{Plans: []string{"free website"}}, true},
+		{"legacy plan ID", ZoneFilter{Plans: []string{"pro", "free"}}, true},
+		{"other plan", ZoneFilter{Plans: []string{"enterprise"}}, false},
+		{"excluded", ZoneFilter{NameGlobs: []string{"*"}, ExcludeGlobs: []string{"shop.*"}}, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.filter.Match(zone); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -29,18 +29,28 @@ func normalizeZoneName(name string) string {
 
 // ZoneIDByName retrieves a zone's ID from the name.
 func ZoneIDByName(ctx context.Context, api *cloudflare.API, zoneName string) (string, string, error) {
+	zone, err := ZoneByName(ctx, api, zoneName)
+	if err != nil {
+		return "", "", err
+	}
+
+	return zone.ID, zone.Account.ID, nil
+}
+
+// ZoneByName retrieves a zone by its name.
+func ZoneByName(ctx context.Context, api *cloudflare.API, zoneName string) (cloudflare.Zone, error) {
 	zoneName = normalizeZoneName(zoneName)
 	res, err := api.ListZonesContext(ctx, cloudflare.WithZoneFilters(zoneName, "", ""))
 	if err != nil {
-		return "", "", fmt.Errorf("ListZonesContext command failed: %w", err)
+		return cloudflare.Zone{}, fmt.Errorf("ListZonesContext command failed: %w", err)
 	}
 
 	switch len(res.Result) {
 	case 0:
-		return "", "", errors.New("zone could not be found")
+		return cloudflare.Zone{}, errors.New("zone could not be found")
 	case 1:
-		return res.Result[0].ID, res.Result[0].Account.ID, nil
+		return res.Result[0], nil
 	default:
-		return "", "", errors.New("ambiguous zone name; an account ID might help")
+		return cloudflare.Zone{}, errors.New("ambiguous zone name; an account ID might help")
 	}
 }
@@ -0,0 +1,115 @@
+package cfhelper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/cloudflare/cloudflare-go"
+)
+
+const (
+	ZonesPerPage         = 50
+	DNSRecordsPerPage    = 100
+	LoadBalancersPerPage = 50
+)
+
+// ListZones fetches all zones matching filter page by page. Filter holds
+// query parameters of the list zones API call, e.g. "account.id" or "status".
+func ListZones(ctx context.Context, api *cloudflare.API, filter url.Values) ([]cloudflare.Zone, error) {
+	params := url.Values{}
+	for k, v := range filter {
+		params[k] = v
+	}
+	params.Set("per_page", strconv.Itoa(ZonesPerPage))
+
+	var zones []cloudflare.Zone
+	for page := 1; ; page++ {
+		params.Set("page", strconv.Itoa(page))
+		res, err := api.Raw(ctx, http.MethodGet, "/zones?"+params.Encode(), nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("zones page %d request failed: %w", page, err)
+		}
+
+		var pageZones []cloudflare.Zone
+		if err := json.Unmarshal(res.Result, &pageZones); err != nil {
+			return nil, fmt.Errorf("zones page %d decoding failed: %w", page, err)
+		}
+		zones = append(zones, pageZones...)
+
+		if len(pageZones) < ZonesPerPage {
+			return zones, nil
+		}
+	}
+}
+
+// ListDNSRecords fetches all DNS records of the zone page by page
+func ListDNSRecords(ctx context.Context, api *cloudflare.API, zoneID string) ([]cloudflare.DNSRecord, error) {
+	var records []cloudflare.DNSRecord
+	params := cloudflare.ListDNSRecordsParams{
+		ResultInfo: cloudflare.ResultInfo{
+			Page:    1,
+			PerPage: DNSRecordsPerPage,
+		},
+	}
+
+	for {
+		pageRecords, info, err := api.ListDNSRecords(ctx, cloudflare.ZoneIdentifier(zoneID), params)
+		if err != nil {
+			return nil, fmt.Errorf("DNS records page %d request failed: %w", params.Page, err)
+		}
+		records = append(records, pageRecords...)
+
+		if info == nil || !info.HasMorePages() || len(pageRecords) == 0 {
+			return records, nil
+		}
+		params.Page++
+	}
+}
+
+// ListLoadBalancers fetches all load balancers of the zone page by page
+func ListLoadBalancers(ctx context.Context, api *cloudflare.API, zoneID string) ([]cloudflare.LoadBalancer, error) {
+	var lbs []cloudflare.LoadBalancer
+	params := cloudflare.ListLoadBalancerParams{
+		PaginationOptions: cloudflare.PaginationOptions{
+			Page:    1,
+			PerPage: LoadBalancersPerPage,
+		},
+	}
+
+	for {
+		pageLBs, err := api.ListLoadBalancers(ctx, cloudflare.ZoneIdentifier(zoneID), params)
+		if err != nil {
+			return nil, fmt.Errorf("load balancers page %d request failed: %w", params.Page, err)
+		}
+		lbs = append(lbs, pageLBs...)
+
+		// API response doesn't expose result info here, so short page
+		// is the end of list
+		if len(pageLBs) < LoadBalancersPerPage {
+			return lbs, nil
+		}
+		params.Page++
+	}
+}
+
+// ListCustomHostnames fetches all Cloudflare for SaaS custom hostnames of
+// the zone page by page
+func ListCustomHostnames(ctx context.Context, api *cloudflare.API, zoneID string) ([]cloudflare.CustomHostname, error) {
+	var hostnames []cloudflare.CustomHostname
+
+	for page := 1; ; page++ {
+		pageHostnames, info, err := api.CustomHostnames(ctx, zoneID, page, cloudflare.CustomHostname{})
+		if err != nil {
+			return nil, fmt.Errorf("custom hostnames page %d request failed: %w", page, err)
+		}
+		hostnames = append(hostnames, pageHostnames...)
+
+		if !info.HasMorePages() || len(pageHostnames) == 0 {
+			return hostnames, nil
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package cfhelper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/cloudflare/cloudflare-go"
+)
+
+// fakeAPI serves paginated listings of generated items like Cloudflare
+// API does and records queries it got
+type fakeAPI struct {
+	total    int
+	mux      sync.Mutex
+	requests []url.Values
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	f.mux.Lock()
+	f.requests = append(f.requests, query)
+	f.mux.Unlock()
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	perPage, _ := strconv.Atoi(query.Get("per_page"))
+	if page < 1 || perPage < 1 {
+		http.Error(w, "pagination parameters are missing", http.StatusBadRequest)
+		return
+	}
+
+	items := []map[string]interface{}{}
+	for i := (page - 1) * perPage; i < page*perPage && i < f.total; i++ {
+		items = append(items, map[string]interface{}{
+			"id":   fmt.Sprintf("id%d", i),
+			"name": fmt.Sprintf("item%d.example.com", i),
+		})
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"errors":   []interface{}{},
+		"messages": []interface{}{},
+		"result":   items,
+		"result_info": map[string]interface{}{
+			"page":        page,
+			"per_page":    perPage,
+			"count":       len(items),
+			"total_count": f.total,
+			"total_pages": (f.total + perPage - 1) / perPage,
+		},
+	})
+}
+
+func newFakeAPI(t *testing.T, total int) (*fakeAPI, *cloudflare.API) {
+	t.Helper()
+	fake := &fakeAPI{total: total}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	api, err := cloudflare.NewWithAPIToken("token", cloudflare.BaseURL(srv.URL), cloudflare.UsingRateLimit(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fake, api
+}
+
+func TestListZones(t *testing.T) {
+	tests := []struct {
+		name     string
+		total    int
+		requests int
+	}{
+		{"empty", 0, 1},
+		{"short page", 3, 1},
+		{"exactly full page", ZonesPerPage, 2},
+		{"last page", ZonesPerPage + 3, 2},
+		{"several pages", 2*ZonesPerPage + 1, 3},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fake, api := newFakeAPI(t, tc.total)
+			zones, err := ListZones(context.Background(), api, url.Values{"status": []string{"active"}})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(zones) != tc.total {
+				t.Errorf("got %d zones, want %d", len(zones), tc.total)
+			}
+			if len(fake.requests) != tc.requests {
+				t.Errorf("made %d requests, want %d", len(fake.requests), tc.requests)
+			}
+			for i, q := range fake.requests {
+				if q.Get("status") != "active" || q.Get("page") != strconv.Itoa(i+1) {
+					t.Errorf("unexpected query of request #%d: %v", i, q)
+				}
+			}
+		})
+	}
+}
+
+func TestListDNSRecords(t *testing.T) {
+	tests := []struct {
+		name     string
+		total    int
+		requests int
+	}{
+		{"empty", 0, 1},
+		{"short page", 5, 1},
+		{"exactly full page", DNSRecordsPerPage, 1},
+		{"last page", DNSRecordsPerPage + 5, 2},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fake, api := newFakeAPI(t, tc.total)
+			recs, err := ListDNSRecords(context.Background(), api, "zone")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(recs) != tc.total {
+				t.Errorf("got %d records, want %d", len(recs), tc.total)
+			}
+			if len(fake.requests) != tc.requests {
+				t.Errorf("made %d requests, want %d", len(fake.requests), tc.requests)
+			}
+		})
+	}
+}
+
+func TestListLoadBalancers(t *testing.T) {
+	tests := []struct {
+		name     string
+		total    int
+		requests int
+	}{
+		{"empty", 0, 1},
+		{"short page", 2, 1},
+		{"exactly full page", LoadBalancersPerPage, 2},
+		{"last page", LoadBalancersPerPage + 2, 2},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fake, api := newFakeAPI(t, tc.total)
+			lbs, err := ListLoadBalancers(context.Background(), api, "zone")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(lbs) != tc.total {
+				t.Errorf("got %d load balancers, want %d", len(lbs), tc.total)
+			}
+			if len(fake.requests) != tc.requests {
+				t.Errorf("made %d requests, want %d", len(fake.requests), tc.requests)
+			}
+		})
+	}
+}
+
+func TestListZonesError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"success":false,"errors":[{"code":9109,"message":"Unauthorized to access requested resource"}],"messages":[],"result":null}`))
+	}))
+	defer srv.Close()
+
+	api, err := cloudflare.NewWithAPIToken("token", cloudflare.BaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ListZones(context.Background(), api, nil); err == nil {
+		t.Error("error expected")
+	}
+}
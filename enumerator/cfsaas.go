@@ -0,0 +1,109 @@
+package enumerator
+
+// Cloudflare for SaaS custom hostnames enumeration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cloudflare/cloudflare-go"
+
+	"github.com/mysteriumnetwork/everssl/enumerator/cfhelper"
+	"github.com/mysteriumnetwork/everssl/target"
+)
+
+// Custom hostname certificate statuses which won't resolve on their own
+var failedCustomHostnameSSLStatuses = map[string]bool{
+	"expired":                true,
+	"initializing_timed_out": true,
+	"validation_timed_out":   true,
+	"issuance_timed_out":     true,
+	"deployment_timed_out":   true,
+	"deletion_timed_out":     true,
+}
+
+// SetScanCustomHostnames enables enumeration of Cloudflare for SaaS custom
+// hostnames
+func (e *CFEnumerator) SetScanCustomHostnames(scan bool) *CFEnumerator {
+	e.scanCustomHostnames = scan
+	return e
+}
+
+// enumerateCustomHostnames returns targets for active custom hostnames and
+// problems with custom hostname certificates reported by Cloudflare
+func (e *CFEnumerator) enumerateCustomHostnames(ctx context.Context, zoneID, zoneName string) ([]target.Target, []error, error) {
+	hostnames, err := cfhelper.ListCustomHostnames(ctx, e.api, zoneID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	source := fmt.Sprintf("Cloudflare for SaaS zone %s", zoneName)
+	var (
+		targets  []target.Target
+		problems []error
+	)
+	for _, hostname := range hostnames {
+		switch hostname.Status {
+		case cloudflare.DELETED, cloudflare.MOVED:
+			continue
+		}
+
+		hostTargets := make([]target.Target, 0, len(e.proxyPorts))
+		for _, port := range e.proxyPorts {
+			hostTargets = append(hostTargets, target.Target{
+				Domain:  hostname.Hostname,
+				Address: "",
+				Port:    port,
+				Source:  source,
+			})
+		}
+
+		// problem belongs to hostname rather than to any of proxy ports,
+		// which may be not checked at all
+		if err := customHostnameProblem(hostname); err != nil {
+			problems = append(problems, &TargetError{
+				Target: target.Target{
+					Domain: hostname.Hostname,
+					Port:   DefaultPort,
+					Source: source,
+				},
+				Err: err,
+			})
+		}
+
+		if hostname.Status == cloudflare.ACTIVE {
+			targets = append(targets, hostTargets...)
+		}
+	}
+
+	return targets, problems, nil
+}
+
+func customHostnameProblem(hostname cloudflare.CustomHostname) error {
+	var msgs []string
+	if len(hostname.VerificationErrors) > 0 {
+		msgs = append(msgs, fmt.Sprintf("hostname verification errors: %s",
+			strings.Join(hostname.VerificationErrors, "; ")))
+	}
+
+	if ssl := hostname.SSL; ssl != nil {
+		if failedCustomHostnameSSLStatuses[ssl.Status] {
+			msgs = append(msgs, fmt.Sprintf("certificate status is %q", ssl.Status))
+		}
+		if len(ssl.ValidationErrors) > 0 {
+			validationMsgs := make([]string, 0, len(ssl.ValidationErrors))
+			for _, ve := range ssl.ValidationErrors {
+				validationMsgs = append(validationMsgs, ve.Message)
+			}
+			msgs = append(msgs, fmt.Sprintf("certificate validation errors: %s",
+				strings.Join(validationMsgs, "; ")))
+		}
+	}
+
+	if len(msgs) == 0 {
+		return nil
+	}
+	return errors.New("Cloudflare reports custom hostname problem: " + strings.Join(msgs, ", "))
+}
@@ -0,0 +1,166 @@
+package enumerator
+
+// Cloudflare Spectrum applications enumeration
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/cloudflare/cloudflare-go"
+
+	"github.com/mysteriumnetwork/everssl/target"
+)
+
+// MaxSpectrumPorts limits number of ports checked for one Spectrum
+// application with port range
+const MaxSpectrumPorts = 16
+
+// SetScanSpectrum enables enumeration of Cloudflare Spectrum applications
+func (e *CFEnumerator) SetScanSpectrum(scan bool) *CFEnumerator {
+	e.scanSpectrum = scan
+	return e
+}
+
+// enumerateSpectrum returns targets for TCP Spectrum applications: edge
+// hostname on edge ports and origins on corresponding origin ports unless
+// Cloudflare talks plaintext to origin. With TLS off Cloudflare passes
+// traffic through, so edge serves origin certificate.
+func (e *CFEnumerator) enumerateSpectrum(ctx context.Context, zoneID, zoneName string, ipv6 bool) ([]target.Target, error) {
+	apps, err := e.api.SpectrumApplications(ctx, zoneID)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []target.Target
+	for _, app := range apps {
+		transport, edgePortSpec, ok := strings.Cut(app.Protocol, "/")
+		if !ok || transport != "tcp" {
+			continue
+		}
+		edgePorts, err := portRange(edgePortSpec)
+		if err != nil {
+			log.Printf("Spectrum app %s: bad protocol %q: %v", app.DNS.Name, app.Protocol, err)
+			continue
+		}
+		if len(edgePorts) > MaxSpectrumPorts {
+			log.Printf("Spectrum app %s: checking only %d of %d ports", app.DNS.Name, MaxSpectrumPorts, len(edgePorts))
+			edgePorts = edgePorts[:MaxSpectrumPorts]
+		}
+
+		source := fmt.Sprintf("Cloudflare Spectrum app %s in zone %s", app.DNS.Name, zoneName)
+		for _, port := range edgePorts {
+			targets = append(targets, target.Target{
+				Domain:  app.DNS.Name,
+				Address: "",
+				Port:    strconv.Itoa(port),
+				Source:  source,
+			})
+		}
+
+		// flexible mode means plaintext between Cloudflare and origin
+		if app.TLS == "flexible" {
+			continue
+		}
+
+		origins, err := spectrumOrigins(app)
+		if err != nil {
+			log.Printf("Spectrum app %s: bad origin: %v", app.DNS.Name, err)
+			continue
+		}
+		for _, origin := range origins {
+			if !ipv6 && isIPv6(origin.host) {
+				continue
+			}
+			for i := range edgePorts {
+				// single origin port serves all edge ports, range maps
+				// edge ports one-to-one
+				port := origin.ports[0]
+				if len(origin.ports) > 1 && i < len(origin.ports) {
+					port = origin.ports[i]
+				}
+				targets = append(targets, target.Target{
+					Domain:  app.DNS.Name,
+					Address: origin.host,
+					Port:    strconv.Itoa(port),
+					Source:  source,
+				})
+			}
+		}
+	}
+
+	return targets, nil
+}
+
+type spectrumOrigin struct {
+	host  string
+	ports []int
+}
+
+func spectrumOrigins(app cloudflare.SpectrumApplication) ([]spectrumOrigin, error) {
+	var origins []spectrumOrigin
+
+	// direct origins look like "tcp://192.0.2.1:22" or "tcp://192.0.2.1:1000-2000"
+	for _, direct := range app.OriginDirect {
+		if idx := strings.Index(direct, "://"); idx >= 0 {
+			direct = direct[idx+3:]
+		}
+		host, portSpec, err := net.SplitHostPort(direct)
+		if err != nil {
+			return nil, err
+		}
+		ports, err := portRange(portSpec)
+		if err != nil {
+			return nil, err
+		}
+		origins = append(origins, spectrumOrigin{
+			host:  host,
+			ports: ports,
+		})
+	}
+
+	if app.OriginDNS != nil && app.OriginDNS.Name != "" && app.OriginPort != nil {
+		var ports []int
+		if app.OriginPort.End > 0 {
+			for p := int(app.OriginPort.Start); p <= int(app.OriginPort.End); p++ {
+				ports = append(ports, p)
+			}
+		} else {
+			ports = []int{int(app.OriginPort.Port)}
+		}
+		origins = append(origins, spectrumOrigin{
+			host:  app.OriginDNS.Name,
+			ports: ports,
+		})
+	}
+
+	return origins, nil
+}
+
+// portRange parses port ("443") or port range ("1000-2000") specification
+func portRange(spec string) ([]int, error) {
+	startSpec, endSpec, isRange := strings.Cut(spec, "-")
+	start, err := strconv.Atoi(startSpec)
+	if err != nil {
+		return nil, err
+	}
+	end := start
+	if isRange {
+		end, err = strconv.Atoi(endSpec)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if start < 1 || end > 65535 || end < start {
+		return nil, fmt.Errorf("bad port range %q", spec)
+	}
+
+	ports := make([]int, 0, end-start+1)
+	for p := start; p <= end; p++ {
+		ports = append(ports, p)
+	}
+	return ports, nil
+}
@@ -0,0 +1,286 @@
+package enumerator
+
+// Enumerator which discovers hostnames from Certificate Transparency log
+// entries (RFC 6962)
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/mysteriumnetwork/everssl/target"
+)
+
+const (
+	DefaultCTBatchSize = 256
+	ctResponseLimit    = 64 * 1024 * 1024
+
+	ctX509Entry    = 0
+	ctPrecertEntry = 1
+)
+
+type ctEntry struct {
+	LeafInput []byte `json:"leaf_input"`
+	ExtraData []byte `json:"extra_data"`
+}
+
+type ctEntries struct {
+	Entries []ctEntry `json:"entries"`
+}
+
+type ctSTH struct {
+	TreeSize int64 `json:"tree_size"`
+}
+
+type CTEnumerator struct {
+	source    string
+	domains   []string
+	start     int64
+	count     int64
+	batchSize int64
+	client    *http.Client
+
+	mux    sync.Mutex
+	loaded bool
+	names  []string
+}
+
+// NewCTEnumerator creates enumerator reading CT log entries from source,
+// which is either a base URL of RFC 6962 log or a path to local dump file.
+// Dump file holds one or more get-entries responses. Domains are registered
+// domains which form the "__all__" zone set.
+func NewCTEnumerator(source string, domains []string) *CTEnumerator {
+	return &CTEnumerator{
+		source:    source,
+		domains:   domains,
+		start:     0,
+		count:     -1,
+		batchSize: DefaultCTBatchSize,
+		client:    &http.Client{},
+	}
+}
+
+// SetWindow limits scanned log entries to count entries beginning at start.
+// Negative start is counted from the end of the log. Negative count means
+// no limit.
+func (e *CTEnumerator) SetWindow(start, count int64) *CTEnumerator {
+	e.start = start
+	e.count = count
+	return e
+}
+
+func (e *CTEnumerator) Enumerate(ctx context.Context, zone string, ipv6 bool) ([]target.Target, error) {
+	zones := []string{zone}
+	if zone == AllZones {
+		if len(e.domains) == 0 {
+			return nil, fmt.Errorf("domains to look up in CT log %s as %s are not listed", e.source, AllZones)
+		}
+		zones = e.domains
+	}
+
+	names, err := e.loadNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []target.Target
+	for _, name := range names {
+		for _, z := range zones {
+			if inZone(name, z) {
+				res = append(res, target.Target{
+					Domain:  name,
+					Address: "",
+					Port:    DefaultPort,
+					Source:  fmt.Sprintf("CT log %s", e.source),
+				})
+				break
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// loadNames reads log once and remembers all unique hostnames found in it
+func (e *CTEnumerator) loadNames(ctx context.Context) ([]string, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	if e.loaded {
+		return e.names, nil
+	}
+
+	seen := make(map[string]struct{})
+	// first is the index of the first entry in the log
+	collect := func(first int64, entries []ctEntry) {
+		for i, entry := range entries {
+			cert, err := entry.certificate()
+			if err != nil {
+				// one odd entry shouldn't hide the rest of the log
+				log.Printf("Skipping bad entry #%d of CT log %s: %v", first+int64(i), e.source, err)
+				continue
+			}
+			// wildcard names are kept for WildcardEnumerator
+			for _, name := range cert.DNSNames {
+				name = strings.ToLower(strings.TrimSuffix(name, "."))
+				if _, ok := seen[name]; !ok {
+					seen[name] = struct{}{}
+					e.names = append(e.names, name)
+				}
+			}
+		}
+	}
+
+	var err error
+	if strings.HasPrefix(e.source, "http://") || strings.HasPrefix(e.source, "https://") {
+		err = e.fetchLog(ctx, collect)
+	} else {
+		err = e.readDump(collect)
+	}
+	if err != nil {
+		e.names = nil
+		return nil, err
+	}
+
+	e.loaded = true
+	return e.names, nil
+}
+
+func (e *CTEnumerator) readDump(collect func(int64, []ctEntry)) error {
+	f, err := os.Open(e.source)
+	if err != nil {
+		return fmt.Errorf("unable to open CT log dump: %w", err)
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	var first int64
+	for {
+		var batch ctEntries
+		err := dec.Decode(&batch)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("unable to parse CT log dump %q: %w", e.source, err)
+		}
+		collect(first, batch.Entries)
+		first += int64(len(batch.Entries))
+	}
+}
+
+func (e *CTEnumerator) fetchLog(ctx context.Context, collect func(int64, []ctEntry)) error {
+	var sth ctSTH
+	if err := e.getJSON(ctx, "ct/v1/get-sth", nil, &sth); err != nil {
+		return fmt.Errorf("get-sth failed: %w", err)
+	}
+
+	start := e.start
+	if start < 0 {
+		start += sth.TreeSize
+		if start < 0 {
+			start = 0
+		}
+	}
+	end := sth.TreeSize
+	if e.count >= 0 && start+e.count < end {
+		end = start + e.count
+	}
+
+	for start < end {
+		last := start + e.batchSize - 1
+		if last >= end {
+			last = end - 1
+		}
+
+		var batch ctEntries
+		err := e.getJSON(ctx, "ct/v1/get-entries", url.Values{
+			"start": []string{fmt.Sprint(start)},
+			"end":   []string{fmt.Sprint(last)},
+		}, &batch)
+		if err != nil {
+			return fmt.Errorf("get-entries failed: %w", err)
+		}
+		// log may return less entries than requested
+		if len(batch.Entries) == 0 {
+			return fmt.Errorf("get-entries returned no entries for range %d-%d", start, last)
+		}
+		collect(start, batch.Entries)
+
+		start += int64(len(batch.Entries))
+	}
+
+	return nil
+}
+
+func (e *CTEnumerator) getJSON(ctx context.Context, path string, query url.Values, dst interface{}) error {
+	reqURL := strings.TrimSuffix(e.source, "/") + "/" + path
+	if query != nil {
+		reqURL += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("bad HTTP status: %s", resp.Status)
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, ctResponseLimit)).Decode(dst)
+}
+
+// certificate extracts certificate (or precertificate) from log entry
+func (entry *ctEntry) certificate() (*x509.Certificate, error) {
+	// MerkleTreeLeaf: version(1) leaf_type(1) timestamp(8) entry_type(2)
+	leaf := entry.LeafInput
+	if len(leaf) < 12 {
+		return nil, errors.New("leaf input is too short")
+	}
+
+	var der []byte
+	switch binary.BigEndian.Uint16(leaf[10:12]) {
+	case ctX509Entry:
+		der = readASN1Cert(leaf[12:])
+	case ctPrecertEntry:
+		// TBSCertificate in leaf can't be parsed alone, so take full
+		// precertificate from PrecertChainEntry in extra data
+		der = readASN1Cert(entry.ExtraData)
+	default:
+		return nil, errors.New("unknown entry type")
+	}
+	if der == nil {
+		return nil, errors.New("truncated certificate")
+	}
+
+	return x509.ParseCertificate(der)
+}
+
+// readASN1Cert reads certificate prefixed with 24-bit length
+func readASN1Cert(b []byte) []byte {
+	if len(b) < 3 {
+		return nil
+	}
+	length := int(b[0])<<16 | int(b[1])<<8 | int(b[2])
+	if len(b) < 3+length {
+		return nil
+	}
+	return b[3 : 3+length]
+}
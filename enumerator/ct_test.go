@@ -0,0 +1,205 @@
+package enumerator
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/json"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCertDER creates self-signed certificate for DNS names
+func testCertDER(t *testing.T, names ...string) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: names[0]},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     names,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func withLength24(b []byte) []byte {
+	return append([]byte{byte(len(b) >> 16), byte(len(b) >> 8), byte(len(b))}, b...)
+}
+
+// testCTEntry builds log entry of given type for certificate
+func testCTEntry(entryType uint16, der []byte) ctEntry {
+	leaf := make([]byte, 12)
+	binary.BigEndian.PutUint16(leaf[10:], entryType)
+	if entryType == ctX509Entry {
+		return ctEntry{LeafInput: append(leaf, withLength24(der)...)}
+	}
+	// TBSCertificate in leaf is not used, full precertificate is in extra data
+	return ctEntry{
+		LeafInput: append(leaf, withLength24([]byte{0x30, 0x00})...),
+		ExtraData: withLength24(der),
+	}
+}
+
+func testCTLog(t *testing.T) []ctEntry {
+	return []ctEntry{
+		testCTEntry(ctX509Entry, testCertDER(t, "www.example.com", "Example.com.")),
+		testCTEntry(ctPrecertEntry, testCertDER(t, "api.example.com")),
+		{LeafInput: []byte{0, 0}},
+		testCTEntry(ctX509Entry, testCertDER(t, "*.example.com", "www.example.com")),
+		testCTEntry(ctX509Entry, testCertDER(t, "shop.example.org")),
+	}
+}
+
+// startCTLog serves entries with RFC 6962 API returning at most pageSize
+// entries per get-entries request
+func startCTLog(t *testing.T, entries []ctEntry, pageSize int) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/log/ct/v1/get-sth", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(ctSTH{TreeSize: int64(len(entries))})
+	})
+	mux.HandleFunc("/log/ct/v1/get-entries", func(w http.ResponseWriter, r *http.Request) {
+		start, err1 := strconv.Atoi(r.URL.Query().Get("start"))
+		end, err2 := strconv.Atoi(r.URL.Query().Get("end"))
+		if err1 != nil || err2 != nil || start > end || end >= len(entries) {
+			http.Error(w, "bad range", http.StatusBadRequest)
+			return
+		}
+		if end-start+1 > pageSize {
+			end = start + pageSize - 1
+		}
+		json.NewEncoder(w).Encode(ctEntries{Entries: entries[start : end+1]})
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCTEnumerator(t *testing.T) {
+	entries := testCTLog(t)
+	srv := startCTLog(t, entries, 2)
+
+	dump := filepath.Join(t.TempDir(), "dump.json")
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.Encode(ctEntries{Entries: entries[:2]})
+	enc.Encode(ctEntries{Entries: entries[2:]})
+	if err := os.WriteFile(dump, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		source  string
+		start   int64
+		count   int64
+		zone    string
+		want    []string
+		skipped string
+	}{
+		{
+			name:    "live log",
+			source:  srv.URL + "/log/",
+			count:   -1,
+			zone:    "example.com",
+			want:    []string{"www.example.com", "example.com", "api.example.com", "*.example.com"},
+			skipped: "entry #2 ",
+		},
+		{
+			name:   "window from the end",
+			source: srv.URL + "/log",
+			start:  -2,
+			count:  -1,
+			zone:   AllZones,
+			want:   []string{"*.example.com", "www.example.com", "shop.example.org"},
+		},
+		{
+			name:   "limited window",
+			source: srv.URL + "/log",
+			start:  1,
+			count:  1,
+			zone:   "example.com",
+			want:   []string{"api.example.com"},
+		},
+		{
+			name:    "dump file",
+			source:  dump,
+			count:   -1,
+			zone:    "example.org",
+			want:    []string{"shop.example.org"},
+			skipped: "entry #2 ",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var logBuf bytes.Buffer
+			log.SetOutput(&logBuf)
+			defer log.SetOutput(os.Stderr)
+
+			e := NewCTEnumerator(tc.source, []string{"example.com", "example.org"}).SetWindow(tc.start, tc.count)
+			e.batchSize = 3
+
+			got, err := e.Enumerate(context.Background(), tc.zone, false)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var names []string
+			for _, tgt := range got {
+				if tgt.Port != DefaultPort || tgt.Address != "" || !strings.HasPrefix(tgt.Source, "CT log ") {
+					t.Errorf("unexpected target %+v", tgt)
+				}
+				names = append(names, tgt.Domain)
+			}
+			if strings.Join(names, ",") != strings.Join(tc.want, ",") {
+				t.Errorf("got names %v, want %v", names, tc.want)
+			}
+			if tc.skipped != "" && !strings.Contains(logBuf.String(), tc.skipped) {
+				t.Errorf("skipped %q is not logged: %s", tc.skipped, logBuf.String())
+			}
+		})
+	}
+}
+
+func TestCTEnumeratorErrors(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	for _, source := range []string{srv.URL, filepath.Join(t.TempDir(), "missing.json")} {
+		_, err := NewCTEnumerator(source, nil).Enumerate(context.Background(), "example.com", false)
+		if err == nil {
+			t.Errorf("error expected for %s", source)
+		}
+	}
+}
+
+func TestCTEnumeratorWithoutDomains(t *testing.T) {
+	srv := startCTLog(t, testCTLog(t), 2)
+
+	_, err := NewCTEnumerator(srv.URL+"/log", nil).Enumerate(context.Background(), AllZones, false)
+	if !IsFailure(err) {
+		t.Errorf("failure expected, got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package enumerator
+
+// DigitalOcean Domains API
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultDigitalOceanURL = "https://api.digitalocean.com"
+	DigitalOceanPerPage    = 200
+)
+
+type digitalOceanProvider struct{}
+
+type digitalOceanLinks struct {
+	Pages struct {
+		Next string `json:"next"`
+	} `json:"pages"`
+}
+
+// NewDigitalOceanEnumerator creates enumerator of domains hosted by
+// DigitalOcean DNS
+func NewDigitalOceanEnumerator(token string) *DNSProviderEnumerator {
+	return newDNSProviderEnumerator("DigitalOcean", digitalOceanProvider{}, DefaultDigitalOceanURL, http.Header{
+		"Authorization": []string{"Bearer " + token},
+	})
+}
+
+func (digitalOceanProvider) firstPage() url.Values {
+	return url.Values{
+		"per_page": []string{strconv.Itoa(DigitalOceanPerPage)},
+	}
+}
+
+func (p digitalOceanProvider) listZones(ctx context.Context, client *restClient) ([]string, error) {
+	var zones []string
+	path, query := "/v2/domains", p.firstPage()
+	for path != "" {
+		var page struct {
+			Domains []struct {
+				Name string `json:"name"`
+			} `json:"domains"`
+			Links digitalOceanLinks `json:"links"`
+		}
+		if err := client.getJSON(ctx, path, query, &page); err != nil {
+			return nil, err
+		}
+		for _, d := range page.Domains {
+			zones = append(zones, d.Name)
+		}
+
+		// next page link carries all query parameters
+		path, query = page.Links.Pages.Next, nil
+	}
+	return zones, nil
+}
+
+func (p digitalOceanProvider) listRecords(ctx context.Context, client *restClient, zone string) ([]dnsRecord, error) {
+	var recs []dnsRecord
+	path, query := "/v2/domains/"+url.PathEscape(zone)+"/records", p.firstPage()
+	for path != "" {
+		var page struct {
+			Records []struct {
+				Type string `json:"type"`
+				Name string `json:"name"`
+				Data string `json:"data"`
+			} `json:"domain_records"`
+			Links digitalOceanLinks `json:"links"`
+		}
+		if err := client.getJSON(ctx, path, query, &page); err != nil {
+			return nil, err
+		}
+		for _, r := range page.Records {
+			// hostnames in data are fully qualified, "@" means apex
+			content := r.Data
+			switch r.Type {
+			case "CNAME", "NS", "MX":
+				if content == "@" {
+					content = zone
+				}
+				content = strings.TrimSuffix(content, ".")
+			}
+			recs = append(recs, dnsRecord{
+				Name:    absoluteName(r.Name, zone),
+				Type:    r.Type,
+				Content: content,
+			})
+		}
+
+		path, query = page.Links.Pages.Next, nil
+	}
+	return recs, nil
+}
@@ -0,0 +1,194 @@
+package enumerator
+
+// Enumerator of DNS hosting providers with REST API
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/mysteriumnetwork/everssl/target"
+)
+
+const restResponseLimit = 64 * 1024 * 1024
+
+// dnsProvider lists zones and records of particular DNS hosting API
+type dnsProvider interface {
+	listZones(ctx context.Context, client *restClient) ([]string, error)
+	listRecords(ctx context.Context, client *restClient, zone string) ([]dnsRecord, error)
+}
+
+// DNSProviderEnumerator enumerates zones hosted by DNS provider with REST
+// API. Records are selected by the same rules as records of Cloudflare
+// zones without proxying. Zones which fail enumeration of all zones are
+// reported as ZoneErrors, targets of other zones are still returned.
+type DNSProviderEnumerator struct {
+	name     string
+	provider dnsProvider
+	client   *restClient
+	scanMX   bool
+	resolver Resolver
+}
+
+func newDNSProviderEnumerator(name string, provider dnsProvider, baseURL string, header http.Header) *DNSProviderEnumerator {
+	return &DNSProviderEnumerator{
+		name:     name,
+		provider: provider,
+		client: &restClient{
+			baseURL: baseURL,
+			header:  header,
+			client:  &http.Client{},
+		},
+	}
+}
+
+// SetBaseURL overrides API base URL of the provider
+func (e *DNSProviderEnumerator) SetBaseURL(baseURL string) *DNSProviderEnumerator {
+	e.client.baseURL = baseURL
+	return e
+}
+
+// SetScanMX enables enumeration of mail exchangers as SMTP targets
+func (e *DNSProviderEnumerator) SetScanMX(scanMX bool) *DNSProviderEnumerator {
+	e.scanMX = scanMX
+	return e
+}
+
+// SetResolver enables resolution of hostname origins of CNAME records into
+// targets for each IP address. Nil resolver disables resolution.
+func (e *DNSProviderEnumerator) SetResolver(resolver Resolver) *DNSProviderEnumerator {
+	e.resolver = resolver
+	return e
+}
+
+func (e *DNSProviderEnumerator) Enumerate(ctx context.Context, zone string, ipv6 bool) ([]target.Target, error) {
+	zones := []string{strings.TrimSuffix(zone, ".")}
+	if zone == AllZones {
+		var err error
+		zones, err = e.provider.listZones(ctx, e.client)
+		if err != nil {
+			return nil, fmt.Errorf("%s zones listing failed: %w", e.name, err)
+		}
+	}
+
+	var resultErr error
+	targets := make(map[target.Target]struct{})
+	for _, z := range zones {
+		recs, err := e.provider.listRecords(ctx, e.client, z)
+		if err != nil {
+			err = fmt.Errorf("%s records listing of zone %s failed: %w", e.name, z, err)
+			if zone != AllZones {
+				return nil, err
+			}
+			resultErr = multierror.Append(resultErr, &ZoneError{Zone: z, Err: err})
+			continue
+		}
+
+		addRecordTargets(targets, recs, ipv6, e.scanMX, []string{DefaultPort},
+			fmt.Sprintf("%s zone %s", e.name, z))
+	}
+
+	res := make([]target.Target, 0, len(targets))
+	for k := range targets {
+		res = append(res, k)
+	}
+
+	if e.resolver != nil {
+		res = resolveOrigins(ctx, e.resolver, res, ipv6)
+	}
+
+	return target.Merge(res), resultErr
+}
+
+// absoluteName converts record name or hostname content relative to zone
+// into fully qualified name without trailing dot. "@" and empty name mean
+// zone apex.
+func absoluteName(name, zone string) string {
+	switch {
+	case name == "" || name == "@":
+		return zone
+	case strings.HasSuffix(name, "."):
+		return strings.TrimSuffix(name, ".")
+	default:
+		return name + "." + zone
+	}
+}
+
+// mxHost extracts exchanger hostname from MX record content which may be
+// prefixed by preference
+func mxHost(content string) string {
+	fields := strings.Fields(content)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[len(fields)-1]
+}
+
+// restClient performs JSON API requests with provider authentication
+// headers
+type restClient struct {
+	baseURL string
+	header  http.Header
+	client  *http.Client
+}
+
+// getJSON fetches path relative to base URL. Absolute URLs (e.g. pagination
+// links) are fetched as is, but only from the scheme and host of base URL,
+// so credentials aren't sent anywhere else.
+func (c *restClient) getJSON(ctx context.Context, path string, query url.Values, dst interface{}) error {
+	reqURL := path
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		if err := c.checkOrigin(path); err != nil {
+			return err
+		}
+	} else {
+		reqURL = strings.TrimSuffix(c.baseURL, "/") + path
+	}
+	if len(query) > 0 {
+		reqURL += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	for k, v := range c.header {
+		req.Header[k] = v
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("bad HTTP status: %s", resp.Status)
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, restResponseLimit)).Decode(dst)
+}
+
+// checkOrigin ensures absolute URL points to the same scheme and host as
+// base URL
+func (c *restClient) checkOrigin(link string) error {
+	linkURL, err := url.Parse(link)
+	if err != nil {
+		return err
+	}
+	baseURL, err := url.Parse(c.baseURL)
+	if err != nil {
+		return err
+	}
+	if linkURL.Scheme != baseURL.Scheme || !strings.EqualFold(linkURL.Host, baseURL.Host) {
+		return fmt.Errorf("refusing to follow link to %s://%s outside of API base URL", linkURL.Scheme, linkURL.Host)
+	}
+	return nil
+}
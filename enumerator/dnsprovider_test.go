@@ -0,0 +1,310 @@
+package enumerator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/mysteriumnetwork/everssl/target"
+)
+
+// fakeDNSRecord is a record of fake provider zone with name relative to
+// zone and hostnames in content fully qualified without trailing dot
+type fakeDNSRecord struct {
+	Name, Type, Content string
+}
+
+// fakeDNSZones are served by all fake providers. Records of
+// broken.example can't be listed.
+var fakeDNSZones = map[string][]fakeDNSRecord{
+	"good.example": {
+		{"www", "A", "192.0.2.1"},
+		{"@", "A", "192.0.2.2"},
+		{"alias", "CNAME", "www.good.example"},
+		{"@", "MX", "mx.good.example"},
+		{"@", "TXT", "v=spf1 -all"},
+	},
+	"broken.example": nil,
+}
+
+func fakeDNSTargets(provider string) []target.Target {
+	source := provider + " zone good.example"
+	return []target.Target{
+		{Domain: "www.good.example", Address: "192.0.2.1", Port: DefaultPort, Source: source},
+		{Domain: "good.example", Address: "192.0.2.2", Port: DefaultPort, Source: source},
+		{Domain: "alias.good.example", Address: "www.good.example", Port: DefaultPort, Source: source},
+		{Domain: "mx.good.example", Port: "25", Protocol: target.ProtocolSMTP, Source: source},
+	}
+}
+
+func fakeZoneNames() []string {
+	return []string{"broken.example", "good.example"}
+}
+
+func checkHeader(w http.ResponseWriter, r *http.Request, name, value string) bool {
+	if r.Header.Get(name) != value {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+func startFakePowerDNS(t *testing.T) *DNSProviderEnumerator {
+	t.Helper()
+
+	const zonesPath = "/api/v1/servers/localhost/zones"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !checkHeader(w, r, "X-Api-Key", "key") {
+			return
+		}
+		if r.URL.Path == zonesPath {
+			var zones []map[string]string
+			for _, name := range fakeZoneNames() {
+				zones = append(zones, map[string]string{"id": name + ".", "name": name + "."})
+			}
+			json.NewEncoder(w).Encode(zones)
+			return
+		}
+
+		zone := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, zonesPath+"/"), ".")
+		recs, ok := fakeDNSZones[zone]
+		if !ok || recs == nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		var rrsets []map[string]interface{}
+		for _, rec := range recs {
+			content := rec.Content
+			switch rec.Type {
+			case "CNAME":
+				content += "."
+			case "MX":
+				content = "10 " + content + "."
+			}
+			rrsets = append(rrsets, map[string]interface{}{
+				"name": absoluteName(rec.Name, zone) + ".",
+				"type": rec.Type,
+				"records": []map[string]interface{}{
+					{"content": content},
+					{"content": content, "disabled": true},
+				},
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"name": zone + ".", "rrsets": rrsets})
+	}))
+	t.Cleanup(srv.Close)
+
+	return NewPowerDNSEnumerator(srv.URL, "key", "")
+}
+
+// startFakeDigitalOcean serves one item per page with absolute next page
+// links. Links of broken.example records point to foreign host, which
+// must never get API token.
+func startFakeDigitalOcean(t *testing.T) *DNSProviderEnumerator {
+	t.Helper()
+
+	foreign := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("API token is sent to foreign host")
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(foreign.Close)
+
+	var srv *httptest.Server
+	page := func(w http.ResponseWriter, r *http.Request, key string, items []interface{}, nextHost string) {
+		n, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if n < 1 {
+			n = 1
+		}
+		res := map[string]interface{}{key: []interface{}{}}
+		links := map[string]interface{}{}
+		if n <= len(items) {
+			res[key] = items[n-1 : n]
+		}
+		if n < len(items) {
+			links["next"] = fmt.Sprintf("%s%s?page=%d&per_page=1", nextHost, r.URL.Path, n+1)
+		}
+		res["links"] = map[string]interface{}{"pages": links}
+		json.NewEncoder(w).Encode(res)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/domains", func(w http.ResponseWriter, r *http.Request) {
+		if !checkHeader(w, r, "Authorization", "Bearer token") {
+			return
+		}
+		var domains []interface{}
+		for _, name := range fakeZoneNames() {
+			domains = append(domains, map[string]string{"name": name})
+		}
+		page(w, r, "domains", domains, srv.URL)
+	})
+	mux.HandleFunc("/v2/domains/", func(w http.ResponseWriter, r *http.Request) {
+		if !checkHeader(w, r, "Authorization", "Bearer token") {
+			return
+		}
+		zone := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v2/domains/"), "/records")
+		recs, ok := fakeDNSZones[zone]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		nextHost := srv.URL
+		if recs == nil {
+			recs = fakeDNSZones["good.example"]
+			nextHost = foreign.URL
+		}
+		var items []interface{}
+		for _, rec := range recs {
+			items = append(items, map[string]string{"name": rec.Name, "type": rec.Type, "data": rec.Content})
+		}
+		page(w, r, "domain_records", items, nextHost)
+	})
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return NewDigitalOceanEnumerator("token").SetBaseURL(srv.URL)
+}
+
+// startFakeHetzner serves one item per page
+func startFakeHetzner(t *testing.T) *DNSProviderEnumerator {
+	t.Helper()
+
+	page := func(w http.ResponseWriter, r *http.Request, key string, items []interface{}) {
+		n, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		res := map[string]interface{}{key: []interface{}{}}
+		if n >= 1 && n <= len(items) {
+			res[key] = items[n-1 : n]
+		}
+		res["meta"] = map[string]interface{}{"pagination": map[string]int{"last_page": len(items)}}
+		json.NewEncoder(w).Encode(res)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/zones", func(w http.ResponseWriter, r *http.Request) {
+		if !checkHeader(w, r, "Auth-Api-Token", "token") {
+			return
+		}
+		var zones []interface{}
+		for _, name := range fakeZoneNames() {
+			if q := r.URL.Query().Get("name"); q == "" || q == name {
+				zones = append(zones, map[string]string{"id": "id-" + name, "name": name})
+			}
+		}
+		page(w, r, "zones", zones)
+	})
+	mux.HandleFunc("/api/v1/records", func(w http.ResponseWriter, r *http.Request) {
+		if !checkHeader(w, r, "Auth-Api-Token", "token") {
+			return
+		}
+		zone := strings.TrimPrefix(r.URL.Query().Get("zone_id"), "id-")
+		recs := fakeDNSZones[zone]
+		if recs == nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		var items []interface{}
+		for _, rec := range recs {
+			value := rec.Content
+			switch rec.Type {
+			case "CNAME":
+				// relative to zone
+				value = strings.TrimSuffix(value, "."+zone)
+			case "MX":
+				value = "10 " + value + "."
+			}
+			items = append(items, map[string]string{"name": rec.Name, "type": rec.Type, "value": value})
+		}
+		page(w, r, "records", items)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return NewHetznerEnumerator("token").SetBaseURL(srv.URL)
+}
+
+func TestDNSProviderEnumerator(t *testing.T) {
+	providers := []struct {
+		name  string
+		start func(t *testing.T) *DNSProviderEnumerator
+	}{
+		{"PowerDNS", startFakePowerDNS},
+		{"DigitalOcean", startFakeDigitalOcean},
+		{"Hetzner", startFakeHetzner},
+	}
+
+	for _, p := range providers {
+		t.Run(p.name, func(t *testing.T) {
+			tests := []struct {
+				name        string
+				zone        string
+				want        []target.Target
+				wantZoneErr string
+				wantFailed  bool
+			}{
+				{
+					name:        "all zones",
+					zone:        AllZones,
+					want:        fakeDNSTargets(p.name),
+					wantZoneErr: "broken.example",
+				},
+				{
+					name: "single zone",
+					zone: "good.example.",
+					want: fakeDNSTargets(p.name),
+				},
+				{
+					name:       "single broken zone",
+					zone:       "broken.example",
+					wantFailed: true,
+				},
+			}
+
+			for _, tc := range tests {
+				t.Run(tc.name, func(t *testing.T) {
+					e := p.start(t).SetScanMX(true)
+					got, err := e.Enumerate(context.Background(), tc.zone, false)
+					if tc.wantFailed {
+						if !IsFailure(err) {
+							t.Fatalf("failure expected, got %v", err)
+						}
+						return
+					}
+					if IsFailure(err) {
+						t.Fatalf("unexpected failure: %v", err)
+					}
+					assertTargets(t, got, tc.want)
+
+					var failedZones []string
+					if merr, ok := err.(*multierror.Error); ok {
+						for _, e := range merr.Errors {
+							var zoneErr *ZoneError
+							if errors.As(e, &zoneErr) {
+								failedZones = append(failedZones, zoneErr.Zone)
+							}
+						}
+					}
+					if strings.Join(failedZones, ",") != tc.wantZoneErr {
+						t.Errorf("got failed zones %v, want %q", failedZones, tc.wantZoneErr)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestRestClientForeignLink(t *testing.T) {
+	e := startFakeDigitalOcean(t)
+	if _, err := e.Enumerate(context.Background(), "broken.example", false); err == nil {
+		t.Error("error expected")
+	}
+}
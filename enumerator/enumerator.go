@@ -2,10 +2,89 @@ package enumerator
 
 import (
 	"context"
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
 
 	"github.com/mysteriumnetwork/everssl/target"
 )
 
+// AllZones is a special zone name which requests all zones available
+// to enumerator
+const AllZones = "__all__"
+
+// Enumerator lists targets of the zone. Enumerator may return targets along
+// with non-nil error if only some part of enumeration failed.
 type Enumerator interface {
 	Enumerate(ctx context.Context, zone string, ipv6 bool) ([]target.Target, error)
 }
+
+// ZoneError describes failure to enumerate particular zone. It allows to
+// attribute errors to zones when several zones are enumerated at once.
+type ZoneError struct {
+	Zone string
+	Err  error
+}
+
+func (e *ZoneError) Error() string {
+	return fmt.Sprintf("zone %s: %v", e.Zone, e.Err)
+}
+
+func (e *ZoneError) Unwrap() error {
+	return e.Err
+}
+
+// TargetError describes problem with particular target detected during
+// enumeration, e.g. reported by provider. It doesn't mean enumeration failure.
+type TargetError struct {
+	Target target.Target
+	Err    error
+}
+
+func (e *TargetError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Target.Domain, e.Err)
+}
+
+func (e *TargetError) Unwrap() error {
+	return e.Err
+}
+
+// FileError describes local file which can't be loaded during enumeration.
+// Targets found in other files are still usable. Err is expected to name
+// the file.
+type FileError struct {
+	Path string
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// IsFailure checks if error returned by enumerator means failure of
+// enumeration rather than just problems found with some targets or files or
+// failure of some zones. Remaining targets are usable in the latter case.
+// Failures covered by cached targets are not failures either.
+func IsFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	errs := []error{err}
+	if merr, ok := err.(*multierror.Error); ok {
+		errs = merr.Errors
+	}
+	for _, e := range errs {
+		switch e.(type) {
+		case *TargetError, *FileError, *ZoneError, *CacheFallbackError:
+		default:
+			return true
+		}
+	}
+
+	return false
+}
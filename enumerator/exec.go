@@ -0,0 +1,181 @@
+package enumerator
+
+// Enumerator which delegates enumeration to external command
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mysteriumnetwork/everssl/target"
+)
+
+const (
+	DefaultExecTimeout = time.Minute
+	// execStderrLimit limits command stderr quoted in errors
+	execStderrLimit = 4096
+)
+
+// ExecEnumerator runs external command with zone name as the last argument.
+// Command writes targets to stdout as newline-delimited JSON objects of the
+// same form as entries of JSON inventory file. Non-zero exit status is an
+// enumeration failure, targets printed before it are still used.
+type ExecEnumerator struct {
+	command string
+	args    []string
+	timeout time.Duration
+}
+
+func NewExecEnumerator(command string, args ...string) *ExecEnumerator {
+	return &ExecEnumerator{
+		command: command,
+		args:    args,
+		timeout: DefaultExecTimeout,
+	}
+}
+
+// SetTimeout limits run time of the command. Zero means no limit.
+func (e *ExecEnumerator) SetTimeout(timeout time.Duration) *ExecEnumerator {
+	e.timeout = timeout
+	return e
+}
+
+func (e *ExecEnumerator) Enumerate(ctx context.Context, zone string, ipv6 bool) ([]target.Target, error) {
+	if e.timeout > 0 {
+		var cl context.CancelFunc
+		ctx, cl = context.WithTimeout(ctx, e.timeout)
+		defer cl()
+	}
+
+	args := append(append([]string{}, e.args...), zone)
+	cmd := exec.CommandContext(ctx, e.command, args...)
+	stdout, stderr, runErr := runCommand(ctx, cmd)
+
+	source := fmt.Sprintf("command %s", e.command)
+	targets, parseErr := parseExecOutput(stdout, ipv6, source)
+
+	if runErr != nil {
+		var exitErr *exec.ExitError
+		switch {
+		case ctx.Err() == context.DeadlineExceeded:
+			runErr = fmt.Errorf("command %s timed out after %v", e.command, e.timeout)
+		case errors.As(runErr, &exitErr):
+			runErr = fmt.Errorf("command %s exited with code %d", e.command, exitErr.ExitCode())
+		default:
+			runErr = fmt.Errorf("command %s failed: %w", e.command, runErr)
+		}
+		if msg := stderrTail(stderr); msg != "" {
+			runErr = fmt.Errorf("%w, stderr: %s", runErr, msg)
+		}
+		return targets, runErr
+	}
+
+	if msg := stderrTail(stderr); msg != "" {
+		log.Printf("Command %s stderr for zone %s: %s", e.command, zone, msg)
+	}
+
+	if parseErr != nil {
+		return targets, fmt.Errorf("bad output of command %s: %w", e.command, parseErr)
+	}
+
+	return targets, nil
+}
+
+// runCommand runs command and collects its output. Once context is done,
+// the command is killed along with its descendants, which may hold output
+// pipes open, and pipes are not waited for.
+func runCommand(ctx context.Context, cmd *exec.Cmd) (*bytes.Buffer, *bytes.Buffer, error) {
+	var stdout, stderr bytes.Buffer
+	stdoutPipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return &stdout, &stderr, err
+	}
+	stderrPipe, err := cmd.StderrPipe()
+	if err != nil {
+		return &stdout, &stderr, err
+	}
+
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return &stdout, &stderr, err
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		io.Copy(&stdout, stdoutPipe)
+	}()
+	go func() {
+		defer wg.Done()
+		io.Copy(&stderr, stderrPipe)
+	}()
+	copied := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(copied)
+	}()
+
+	select {
+	case <-copied:
+	case <-ctx.Done():
+		killProcessGroup(cmd)
+	}
+	// Wait closes pipes, so copying finishes in any case
+	err = cmd.Wait()
+	<-copied
+
+	return &stdout, &stderr, err
+}
+
+// parseExecOutput decodes targets line by line. Decoding stops at first bad
+// line, targets of preceding lines are returned along with error.
+func parseExecOutput(stdout *bytes.Buffer, ipv6 bool, source string) ([]target.Target, error) {
+	seen := make(map[target.Target]struct{})
+	var res []target.Target
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(nil, 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var spec targetSpec
+		if err := json.Unmarshal(line, &spec); err != nil {
+			return res, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		specTargets, err := spec.targets(ipv6, source)
+		if err != nil {
+			return res, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		for _, t := range specTargets {
+			if _, ok := seen[t]; !ok {
+				seen[t] = struct{}{}
+				res = append(res, t)
+			}
+		}
+	}
+
+	return res, scanner.Err()
+}
+
+func stderrTail(stderr *bytes.Buffer) string {
+	msg := stderr.Bytes()
+	if len(msg) > execStderrLimit {
+		msg = msg[len(msg)-execStderrLimit:]
+	}
+	return strings.TrimSpace(string(msg))
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+package enumerator
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup does nothing where process groups are not supported
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
@@ -0,0 +1,94 @@
+package enumerator
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/everssl/target"
+)
+
+func TestExecEnumerator(t *testing.T) {
+	source := "command sh"
+	tests := []struct {
+		name    string
+		script  string
+		timeout time.Duration
+		want    []target.Target
+		wantErr string
+	}{
+		{
+			name: "targets of requested zone",
+			script: `echo '{"domain": "'"$1"'"}'
+echo
+echo '{"domain": "mail.'"$1"'", "port": 25, "protocol": "smtp", "address": "192.0.2.1"}'
+echo '{"domain": "mail.'"$1"'", "port": 25, "protocol": "smtp", "address": "2001:db8::1"}'
+echo 'debug output' >&2`,
+			want: []target.Target{
+				{Domain: "example.com", Port: DefaultPort, Source: source},
+				{Domain: "mail.example.com", Address: "192.0.2.1", Port: "25", Protocol: target.ProtocolSMTP, Source: source},
+			},
+		},
+		{
+			name: "bad line",
+			script: `echo '{"domain": "www.example.com"}'
+echo 'not JSON'
+echo '{"domain": "api.example.com"}'`,
+			want:    []target.Target{{Domain: "www.example.com", Port: DefaultPort, Source: source}},
+			wantErr: "line 2",
+		},
+		{
+			name: "non-zero exit",
+			script: `echo '{"domain": "www.example.com"}'
+echo 'first complaint' >&2
+echo 'API is down' >&2
+exit 3`,
+			want:    []target.Target{{Domain: "www.example.com", Port: DefaultPort, Source: source}},
+			wantErr: "exited with code 3, stderr: first complaint\nAPI is down",
+		},
+		{
+			name: "timeout",
+			script: `echo '{"domain": "www.example.com"}'
+echo 'still working' >&2
+sleep 30`,
+			timeout: 200 * time.Millisecond,
+			want:    []target.Target{{Domain: "www.example.com", Port: DefaultPort, Source: source}},
+			wantErr: "timed out after 200ms, stderr: still working",
+		},
+		{
+			name: "timeout with background child holding stdout",
+			script: `echo '{"domain": "www.example.com"}'
+sleep 30 &
+sleep 30`,
+			timeout: 200 * time.Millisecond,
+			want:    []target.Target{{Domain: "www.example.com", Port: DefaultPort, Source: source}},
+			wantErr: "timed out",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e := NewExecEnumerator("sh", "-c", tc.script, "sh")
+			if tc.timeout > 0 {
+				e.SetTimeout(tc.timeout)
+			}
+
+			start := time.Now()
+			got, err := e.Enumerate(context.Background(), "example.com", false)
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("command took %v", elapsed)
+			}
+			assertTargets(t, got, tc.want)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("got error %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
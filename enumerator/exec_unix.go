@@ -0,0 +1,18 @@
+//go:build unix
+
+package enumerator
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup makes command a leader of new process group, so that
+// killProcessGroup reaches its descendants as well
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+func killProcessGroup(cmd *exec.Cmd) {
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
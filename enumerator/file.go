@@ -0,0 +1,192 @@
+package enumerator
+
+// Enumerator which reads targets from static inventory file
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/mysteriumnetwork/everssl/target"
+)
+
+// targetSpec is a serialized form of target used in structured inventories
+type targetSpec struct {
+	Domain    string   `json:"domain" yaml:"domain"`
+	Port      int      `json:"port" yaml:"port"`
+	Protocol  string   `json:"protocol" yaml:"protocol"`
+	Address   string   `json:"address" yaml:"address"`
+	Addresses []string `json:"addresses" yaml:"addresses"`
+	// TrustStore names trust store to verify certificates against
+	TrustStore string `json:"trust_store" yaml:"trust_store"`
+}
+
+type inventory struct {
+	Targets []targetSpec `json:"targets" yaml:"targets"`
+}
+
+func (s *targetSpec) targets(ipv6 bool, source string) ([]target.Target, error) {
+	if s.Domain == "" {
+		return nil, fmt.Errorf("domain is not specified")
+	}
+
+	protocol, err := target.ParseProtocol(s.Protocol)
+	if err != nil {
+		return nil, err
+	}
+
+	port := protocol.DefaultPort()
+	if s.Port != 0 {
+		if s.Port < 0 || s.Port > 65535 {
+			return nil, fmt.Errorf("bad port number %d", s.Port)
+		}
+		port = strconv.Itoa(s.Port)
+	}
+
+	addresses := s.Addresses
+	if s.Address != "" {
+		addresses = append([]string{s.Address}, addresses...)
+	}
+	if len(addresses) == 0 {
+		addresses = []string{""}
+	}
+
+	var res []target.Target
+	for _, addr := range addresses {
+		if !ipv6 && isIPv6(addr) {
+			continue
+		}
+		res = append(res, target.Target{
+			Domain:     s.Domain,
+			Address:    addr,
+			Port:       port,
+			Protocol:   protocol,
+			Source:     source,
+			TrustStore: s.TrustStore,
+		})
+	}
+
+	return res, nil
+}
+
+// FileEnumerator reads targets from inventory file. File format is chosen
+// by extension: ".yaml" and ".yml" for YAML, ".json" for JSON and plain
+// text otherwise. Plain text inventory contains one "domain[:port][@address]"
+// entry per line. Empty lines and lines starting with "#" are ignored.
+type FileEnumerator struct {
+	path string
+}
+
+func NewFileEnumerator(path string) *FileEnumerator {
+	return &FileEnumerator{
+		path: path,
+	}
+}
+
+func (e *FileEnumerator) Enumerate(ctx context.Context, zone string, ipv6 bool) ([]target.Target, error) {
+	f, err := os.Open(e.path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open inventory file: %w", err)
+	}
+	defer f.Close()
+
+	var specs []targetSpec
+	switch strings.ToLower(filepath.Ext(e.path)) {
+	case ".yaml", ".yml":
+		var inv inventory
+		if err := yaml.NewDecoder(f).Decode(&inv); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("unable to parse YAML inventory %q: %w", e.path, err)
+		}
+		specs = inv.Targets
+	case ".json":
+		var inv inventory
+		if err := json.NewDecoder(f).Decode(&inv); err != nil {
+			return nil, fmt.Errorf("unable to parse JSON inventory %q: %w", e.path, err)
+		}
+		specs = inv.Targets
+	default:
+		specs, err = parsePlainInventory(f)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse inventory %q: %w", e.path, err)
+		}
+	}
+
+	seen := make(map[target.Target]struct{})
+	var res []target.Target
+	for i, spec := range specs {
+		if !inZone(spec.Domain, zone) {
+			continue
+		}
+		specTargets, err := spec.targets(ipv6, fmt.Sprintf("inventory file %s", e.path))
+		if err != nil {
+			return nil, fmt.Errorf("bad target #%d in inventory %q: %w", i+1, e.path, err)
+		}
+		for _, t := range specTargets {
+			if _, ok := seen[t]; !ok {
+				seen[t] = struct{}{}
+				res = append(res, t)
+			}
+		}
+	}
+
+	return res, nil
+}
+
+func parsePlainInventory(r io.Reader) ([]targetSpec, error) {
+	var specs []targetSpec
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var spec targetSpec
+		hostPort := line
+		if idx := strings.IndexByte(line, '@'); idx >= 0 {
+			hostPort, spec.Address = line[:idx], line[idx+1:]
+		}
+		spec.Domain = hostPort
+		if idx := strings.LastIndexByte(hostPort, ':'); idx >= 0 {
+			port, err := strconv.Atoi(hostPort[idx+1:])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: bad port: %w", lineNo, err)
+			}
+			spec.Domain, spec.Port = hostPort[:idx], port
+		}
+
+		specs = append(specs, spec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return specs, nil
+}
+
+// inZone checks if domain belongs to zone
+func inZone(domain, zone string) bool {
+	if zone == AllZones {
+		return true
+	}
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	zone = strings.ToLower(strings.TrimSuffix(zone, "."))
+	return domain == zone || strings.HasSuffix(domain, "."+zone)
+}
+
+func isIPv6(addr string) bool {
+	ip := net.ParseIP(addr)
+	return ip != nil && ip.To4() == nil
+}
@@ -0,0 +1,167 @@
+package enumerator
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/mysteriumnetwork/everssl/target"
+)
+
+const testPlainInventory = `# web servers
+www.example.com
+api.example.com:8443@192.0.2.10
+api.example.com:8443@2001:db8::10
+
+mail.example.org:465
+`
+
+const testYAMLInventory = `targets:
+  - domain: www.example.com
+    addresses: [192.0.2.10, "2001:db8::10"]
+  - domain: mail.example.com
+    protocol: smtp
+    address: 192.0.2.25
+  - domain: intranet.example.com
+    port: 8443
+    trust_store: corp
+  - domain: www.example.org
+`
+
+const testJSONInventory = `{"targets": [
+  {"domain": "www.example.com", "addresses": ["192.0.2.10", "2001:db8::10"]},
+  {"domain": "mail.example.com", "protocol": "smtp", "address": "192.0.2.25"},
+  {"domain": "intranet.example.com", "port": 8443, "trust_store": "corp"},
+  {"domain": "www.example.org"}
+]}`
+
+func TestFileEnumerator(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"inventory.txt":  testPlainInventory,
+		"inventory.yaml": testYAMLInventory,
+		"inventory.json": testJSONInventory,
+	})
+
+	withSource := func(file string, specs ...string) []target.Target {
+		var res []target.Target
+		for _, spec := range specs {
+			tgt := parseTestTarget(spec)
+			tgt.Source = "inventory file " + filepath.Join(dir, file)
+			res = append(res, tgt)
+		}
+		return res
+	}
+	structured := func(file string, ipv6 bool, zone string) []target.Target {
+		specs := []string{
+			"www.example.com@192.0.2.10",
+			"mail.example.com:25/smtp@192.0.2.25",
+		}
+		if ipv6 {
+			specs = append(specs, "www.example.com@2001:db8::10")
+		}
+		if zone == AllZones {
+			specs = append(specs, "www.example.org")
+		}
+		res := append(withSource(file, specs...), withSource(file, "intranet.example.com:8443")...)
+		res[len(res)-1].TrustStore = "corp"
+		return res
+	}
+
+	tests := []struct {
+		name string
+		file string
+		zone string
+		ipv6 bool
+		want []target.Target
+	}{
+		{
+			name: "plain",
+			file: "inventory.txt",
+			zone: AllZones,
+			want: withSource("inventory.txt",
+				"www.example.com",
+				"api.example.com:8443@192.0.2.10",
+				"mail.example.org:465",
+			),
+		},
+		{
+			name: "plain with IPv6",
+			file: "inventory.txt",
+			zone: AllZones,
+			ipv6: true,
+			want: withSource("inventory.txt",
+				"www.example.com",
+				"api.example.com:8443@192.0.2.10",
+				"api.example.com:8443@2001:db8::10",
+				"mail.example.org:465",
+			),
+		},
+		{
+			name: "plain zone filter",
+			file: "inventory.txt",
+			zone: "example.org.",
+			want: withSource("inventory.txt", "mail.example.org:465"),
+		},
+		{
+			name: "YAML",
+			file: "inventory.yaml",
+			zone: AllZones,
+			want: structured("inventory.yaml", false, AllZones),
+		},
+		{
+			name: "YAML with IPv6 and zone filter",
+			file: "inventory.yaml",
+			zone: "Example.com",
+			ipv6: true,
+			want: structured("inventory.yaml", true, "example.com"),
+		},
+		{
+			name: "JSON",
+			file: "inventory.json",
+			zone: AllZones,
+			want: structured("inventory.json", false, AllZones),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NewFileEnumerator(filepath.Join(dir, tc.file)).Enumerate(context.Background(), tc.zone, tc.ipv6)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertTargets(t, got, tc.want)
+		})
+	}
+}
+
+func TestFileEnumeratorErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"bad-port.txt":       "www.example.com:https\n",
+		"port-range.txt":     "www.example.com:65536\n",
+		"port-range.yaml":    "targets:\n  - domain: www.example.com\n    port: -1\n",
+		"no-domain.json":     `{"targets": [{"address": "192.0.2.10"}]}`,
+		"bad-protocol.yaml":  "targets:\n  - domain: www.example.com\n    protocol: gopher\n",
+		"broken.json":        `{"targets": [`,
+		"not-inventory.yaml": "targets: 42\n",
+	})
+
+	tests := []string{
+		"missing.txt",
+		"bad-port.txt",
+		"port-range.txt",
+		"port-range.yaml",
+		"no-domain.json",
+		"bad-protocol.yaml",
+		"broken.json",
+		"not-inventory.yaml",
+	}
+
+	for _, file := range tests {
+		t.Run(file, func(t *testing.T) {
+			_, err := NewFileEnumerator(filepath.Join(dir, file)).Enumerate(context.Background(), AllZones, false)
+			if err == nil {
+				t.Fatal("error expected")
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package enumerator
+
+// Hetzner DNS API
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const (
+	DefaultHetznerURL = "https://dns.hetzner.com"
+	HetznerPerPage    = 100
+)
+
+type hetznerProvider struct{}
+
+type hetznerZone struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type hetznerMeta struct {
+	Pagination struct {
+		LastPage int `json:"last_page"`
+	} `json:"pagination"`
+}
+
+// NewHetznerEnumerator creates enumerator of zones hosted by Hetzner DNS
+func NewHetznerEnumerator(token string) *DNSProviderEnumerator {
+	return newDNSProviderEnumerator("Hetzner", hetznerProvider{}, DefaultHetznerURL, http.Header{
+		"Auth-Api-Token": []string{token},
+	})
+}
+
+func (hetznerProvider) zones(ctx context.Context, client *restClient, name string) ([]hetznerZone, error) {
+	var zones []hetznerZone
+	for page := 1; ; page++ {
+		query := url.Values{
+			"page":     []string{strconv.Itoa(page)},
+			"per_page": []string{strconv.Itoa(HetznerPerPage)},
+		}
+		if name != "" {
+			query.Set("name", name)
+		}
+
+		var resp struct {
+			Zones []hetznerZone `json:"zones"`
+			Meta  hetznerMeta   `json:"meta"`
+		}
+		if err := client.getJSON(ctx, "/api/v1/zones", query, &resp); err != nil {
+			return nil, err
+		}
+		zones = append(zones, resp.Zones...)
+
+		if page >= resp.Meta.Pagination.LastPage || len(resp.Zones) == 0 {
+			return zones, nil
+		}
+	}
+}
+
+func (p hetznerProvider) listZones(ctx context.Context, client *restClient) ([]string, error) {
+	zones, err := p.zones(ctx, client, "")
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]string, 0, len(zones))
+	for _, z := range zones {
+		res = append(res, z.Name)
+	}
+	return res, nil
+}
+
+func (p hetznerProvider) listRecords(ctx context.Context, client *restClient, zone string) ([]dnsRecord, error) {
+	// records are listed by zone ID
+	zones, err := p.zones(ctx, client, zone)
+	if err != nil {
+		return nil, err
+	}
+	if len(zones) == 0 {
+		return nil, fmt.Errorf("zone %s not found", zone)
+	}
+
+	var recs []dnsRecord
+	for page := 1; ; page++ {
+		query := url.Values{
+			"zone_id":  []string{zones[0].ID},
+			"page":     []string{strconv.Itoa(page)},
+			"per_page": []string{strconv.Itoa(HetznerPerPage)},
+		}
+
+		var resp struct {
+			Records []struct {
+				Type  string `json:"type"`
+				Name  string `json:"name"`
+				Value string `json:"value"`
+			} `json:"records"`
+			Meta hetznerMeta `json:"meta"`
+		}
+		if err := client.getJSON(ctx, "/api/v1/records", query, &resp); err != nil {
+			return nil, err
+		}
+		for _, r := range resp.Records {
+			content := r.Value
+			switch r.Type {
+			case "MX":
+				content = absoluteName(mxHost(content), zone)
+			case "CNAME", "NS":
+				content = absoluteName(content, zone)
+			}
+			recs = append(recs, dnsRecord{
+				Name:    absoluteName(r.Name, zone),
+				Type:    r.Type,
+				Content: content,
+			})
+		}
+
+		if page >= resp.Meta.Pagination.LastPage || len(resp.Records) == 0 {
+			return recs, nil
+		}
+	}
+}
@@ -0,0 +1,512 @@
+package enumerator
+
+// Enumerator which reads hostnames from Kubernetes Ingress and Gateway API
+// objects
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/mysteriumnetwork/everssl/target"
+)
+
+const (
+	KubeListLimit     = 500
+	kubeResponseLimit = 64 * 1024 * 1024
+)
+
+// kubeResources are API paths of listed object kinds. Gateway API is
+// optional in cluster, so its absence is not an error.
+var kubeResources = []struct {
+	path     string
+	optional bool
+}{
+	{"/apis/networking.k8s.io/v1/ingresses", false},
+	{"/apis/gateway.networking.k8s.io/v1/gateways", true},
+	{"/apis/gateway.networking.k8s.io/v1/httproutes", true},
+}
+
+// kubeObject holds fields of Ingress, Gateway and HTTPRoute objects and
+// their lists which are relevant for enumeration
+type kubeObject struct {
+	Kind     string `json:"kind" yaml:"kind"`
+	Metadata struct {
+		Name      string `json:"name" yaml:"name"`
+		Namespace string `json:"namespace" yaml:"namespace"`
+		Continue  string `json:"continue" yaml:"continue"`
+	} `json:"metadata" yaml:"metadata"`
+	Spec struct {
+		// Ingress
+		Rules []struct {
+			Host string `json:"host" yaml:"host"`
+		} `json:"rules" yaml:"rules"`
+		TLS []struct {
+			Hosts      []string `json:"hosts" yaml:"hosts"`
+			SecretName string   `json:"secretName" yaml:"secretName"`
+		} `json:"tls" yaml:"tls"`
+
+		// Gateway
+		Listeners []kubeListener `json:"listeners" yaml:"listeners"`
+
+		// HTTPRoute
+		Hostnames  []string `json:"hostnames" yaml:"hostnames"`
+		ParentRefs []struct {
+			Kind        string `json:"kind" yaml:"kind"`
+			Name        string `json:"name" yaml:"name"`
+			Namespace   string `json:"namespace" yaml:"namespace"`
+			SectionName string `json:"sectionName" yaml:"sectionName"`
+			Port        int    `json:"port" yaml:"port"`
+		} `json:"parentRefs" yaml:"parentRefs"`
+	} `json:"spec" yaml:"spec"`
+	Items []kubeObject `json:"items" yaml:"-"`
+}
+
+type kubeListener struct {
+	Name     string `json:"name" yaml:"name"`
+	Hostname string `json:"hostname" yaml:"hostname"`
+	Port     int    `json:"port" yaml:"port"`
+	Protocol string `json:"protocol" yaml:"protocol"`
+	TLS      *struct {
+		CertificateRefs []struct {
+			Name      string `json:"name" yaml:"name"`
+			Namespace string `json:"namespace" yaml:"namespace"`
+		} `json:"certificateRefs" yaml:"certificateRefs"`
+	} `json:"tls" yaml:"tls"`
+}
+
+func (o *kubeObject) namespace() string {
+	if o.Metadata.Namespace == "" {
+		return "default"
+	}
+	return o.Metadata.Namespace
+}
+
+// KubeEnumerator emits TLS hosts of Kubernetes Ingress objects and hosts
+// of HTTPS and TLS listeners of Gateway API gateways. Hostnames of
+// HTTPRoutes attached to listeners without exact hostname are used as
+// well. Wildcard hosts are emitted as is, to be expanded by
+// WildcardEnumerator. Objects are read either from a directory of
+// YAML/JSON manifests or from Kubernetes API server.
+type KubeEnumerator struct {
+	source string
+	token  string
+	client *http.Client
+
+	mux     sync.Mutex
+	loaded  bool
+	targets []target.Target
+}
+
+// NewKubeEnumerator creates enumerator reading objects from source, which
+// is either a base URL of Kubernetes API server or a path to manifests
+// directory.
+func NewKubeEnumerator(source string) *KubeEnumerator {
+	return &KubeEnumerator{
+		source: source,
+		client: &http.Client{},
+	}
+}
+
+// SetToken sets bearer token for Kubernetes API requests
+func (e *KubeEnumerator) SetToken(token string) *KubeEnumerator {
+	e.token = token
+	return e
+}
+
+// SetCAFile makes Kubernetes API client trust CA certificates from the file
+func (e *KubeEnumerator) SetCAFile(path string) (*KubeEnumerator, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read Kubernetes CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in Kubernetes CA file %q", path)
+	}
+	e.client = &http.Client{
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{
+				RootCAs: pool,
+			},
+		},
+	}
+	return e, nil
+}
+
+func (e *KubeEnumerator) Enumerate(ctx context.Context, zone string, ipv6 bool) ([]target.Target, error) {
+	targets, err := e.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []target.Target
+	for _, t := range targets {
+		if inZone(t.Domain, zone) {
+			res = append(res, t)
+		}
+	}
+
+	return res, nil
+}
+
+func (e *KubeEnumerator) load(ctx context.Context) ([]target.Target, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	if e.loaded {
+		return e.targets, nil
+	}
+
+	var (
+		objects []kubeObject
+		err     error
+	)
+	if strings.HasPrefix(e.source, "http://") || strings.HasPrefix(e.source, "https://") {
+		objects, err = e.fetchObjects(ctx)
+	} else {
+		objects, err = readKubeManifests(e.source)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	e.targets = kubeTargets(objects)
+	e.loaded = true
+	return e.targets, nil
+}
+
+func (e *KubeEnumerator) fetchObjects(ctx context.Context) ([]kubeObject, error) {
+	var objects []kubeObject
+	for _, resource := range kubeResources {
+		query := url.Values{}
+		query.Set("limit", strconv.Itoa(KubeListLimit))
+		for {
+			var list kubeObject
+			err := e.getJSON(ctx, resource.path, query, &list)
+			if err != nil {
+				if resource.optional && errors.Is(err, errKubeNotFound) {
+					break
+				}
+				return nil, fmt.Errorf("unable to list %s: %w", resource.path, err)
+			}
+			objects = append(objects, flattenKubeObjects([]kubeObject{list})...)
+
+			if list.Metadata.Continue == "" {
+				break
+			}
+			query.Set("continue", list.Metadata.Continue)
+		}
+	}
+
+	return objects, nil
+}
+
+var errKubeNotFound = errors.New("resource not found")
+
+func (e *KubeEnumerator) getJSON(ctx context.Context, path string, query url.Values, dst interface{}) error {
+	reqURL := strings.TrimSuffix(e.source, "/") + path + "?" + query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if e.token != "" {
+		req.Header.Set("Authorization", "Bearer "+e.token)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return errKubeNotFound
+	default:
+		return fmt.Errorf("bad HTTP status: %s", resp.Status)
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, kubeResponseLimit)).Decode(dst)
+}
+
+// readKubeManifests reads all objects from YAML and JSON files in the
+// directory tree. Files may contain several YAML documents.
+func readKubeManifests(dir string) ([]kubeObject, error) {
+	var objects []kubeObject
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		// YAML is a superset of JSON, so single decoder handles both
+		dec := yaml.NewDecoder(f)
+		for {
+			var node yaml.Node
+			err := dec.Decode(&node)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("unable to parse manifest %q: %w", path, err)
+			}
+			nodeObjects, err := decodeKubeNode(&node, "")
+			if err != nil {
+				return fmt.Errorf("unable to parse manifest %q: %w", path, err)
+			}
+			objects = append(objects, nodeObjects...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read Kubernetes manifests: %w", err)
+	}
+
+	return objects, nil
+}
+
+// decodeKubeNode decodes objects of enumerated kinds from manifest
+// document. Other objects are skipped without decoding as their specs
+// may not fit.
+func decodeKubeNode(node *yaml.Node, kind string) ([]kubeObject, error) {
+	var header struct {
+		Kind  string      `yaml:"kind"`
+		Items []yaml.Node `yaml:"items"`
+	}
+	if err := node.Decode(&header); err != nil {
+		return nil, err
+	}
+	if header.Kind != "" {
+		kind = header.Kind
+	}
+
+	if strings.HasSuffix(kind, "List") {
+		var res []kubeObject
+		for i := range header.Items {
+			items, err := decodeKubeNode(&header.Items[i], strings.TrimSuffix(kind, "List"))
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, items...)
+		}
+		return res, nil
+	}
+
+	switch kind {
+	case "Ingress", "Gateway", "HTTPRoute":
+	default:
+		return nil, nil
+	}
+
+	var obj kubeObject
+	if err := node.Decode(&obj); err != nil {
+		return nil, fmt.Errorf("%s %s: %w", kind, obj.Metadata.Name, err)
+	}
+	obj.Kind = kind
+	return []kubeObject{obj}, nil
+}
+
+// flattenKubeObjects unpacks lists returned by API server. Items of these
+// lists have no kind, so it is derived from list kind.
+func flattenKubeObjects(objects []kubeObject) []kubeObject {
+	var res []kubeObject
+	for _, obj := range objects {
+		if !strings.HasSuffix(obj.Kind, "List") {
+			res = append(res, obj)
+			continue
+		}
+
+		itemKind := strings.TrimSuffix(obj.Kind, "List")
+		for _, item := range obj.Items {
+			if item.Kind == "" {
+				item.Kind = itemKind
+			}
+			res = append(res, item)
+		}
+	}
+	return res
+}
+
+func kubeTargets(objects []kubeObject) []target.Target {
+	var (
+		targets []target.Target
+		routes  []kubeObject
+	)
+	for _, obj := range objects {
+		if obj.Kind == "HTTPRoute" {
+			routes = append(routes, obj)
+		}
+	}
+
+	for _, obj := range objects {
+		switch obj.Kind {
+		case "Ingress":
+			targets = append(targets, ingressTargets(obj)...)
+		case "Gateway":
+			targets = append(targets, gatewayTargets(obj, routes)...)
+		}
+	}
+
+	return target.Merge(targets)
+}
+
+// ingressTargets returns hosts of Ingress TLS sections. TLS section
+// without hosts applies to hosts of all rules.
+func ingressTargets(ing kubeObject) []target.Target {
+	var res []target.Target
+	for _, tlsSpec := range ing.Spec.TLS {
+		hosts := tlsSpec.Hosts
+		if len(hosts) == 0 {
+			for _, rule := range ing.Spec.Rules {
+				hosts = append(hosts, rule.Host)
+			}
+		}
+
+		source := fmt.Sprintf("Kubernetes Ingress %s/%s", ing.namespace(), ing.Metadata.Name)
+		if tlsSpec.SecretName != "" {
+			source += fmt.Sprintf(" (secret %s/%s)", ing.namespace(), tlsSpec.SecretName)
+		}
+		for _, host := range hosts {
+			if host == "" {
+				continue
+			}
+			res = append(res, target.Target{
+				Domain: host,
+				Port:   DefaultPort,
+				Source: source,
+			})
+		}
+	}
+	return res
+}
+
+// gatewayTargets returns hosts of HTTPS and TLS listeners. Listeners
+// without exact hostname serve hostnames of attached routes as well.
+func gatewayTargets(gw kubeObject, routes []kubeObject) []target.Target {
+	var res []target.Target
+	for _, listener := range gw.Spec.Listeners {
+		if listener.Protocol != "HTTPS" && listener.Protocol != "TLS" {
+			continue
+		}
+
+		port := DefaultPort
+		if listener.Port != 0 {
+			port = strconv.Itoa(listener.Port)
+		}
+
+		source := fmt.Sprintf("Kubernetes Gateway %s/%s listener %s", gw.namespace(), gw.Metadata.Name, listener.Name)
+		if listener.TLS != nil {
+			var secrets []string
+			for _, ref := range listener.TLS.CertificateRefs {
+				ns := ref.Namespace
+				if ns == "" {
+					ns = gw.namespace()
+				}
+				secrets = append(secrets, ns+"/"+ref.Name)
+			}
+			if len(secrets) > 0 {
+				source += fmt.Sprintf(" (secret %s)", strings.Join(secrets, ", "))
+			}
+		}
+
+		if listener.Hostname != "" {
+			res = append(res, target.Target{
+				Domain: listener.Hostname,
+				Port:   port,
+				Source: source,
+			})
+			if !strings.HasPrefix(listener.Hostname, "*.") {
+				continue
+			}
+		}
+
+		for _, route := range routes {
+			if !routeAttached(route, gw, listener) {
+				continue
+			}
+			routeSource := fmt.Sprintf("Kubernetes HTTPRoute %s/%s via %s",
+				route.namespace(), route.Metadata.Name, source)
+			for _, host := range route.Spec.Hostnames {
+				if !listenerHostMatch(listener.Hostname, host) {
+					continue
+				}
+				res = append(res, target.Target{
+					Domain: host,
+					Port:   port,
+					Source: routeSource,
+				})
+			}
+		}
+	}
+	return res
+}
+
+func routeAttached(route, gw kubeObject, listener kubeListener) bool {
+	for _, ref := range route.Spec.ParentRefs {
+		if ref.Kind != "" && ref.Kind != "Gateway" {
+			continue
+		}
+		ns := ref.Namespace
+		if ns == "" {
+			ns = route.namespace()
+		}
+		if ref.Name != gw.Metadata.Name || ns != gw.namespace() {
+			continue
+		}
+		if ref.SectionName != "" && ref.SectionName != listener.Name {
+			continue
+		}
+		if ref.Port != 0 && ref.Port != listener.Port {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// listenerHostMatch checks if host is served by listener with hostname
+// pattern, which may be empty or a wildcard
+func listenerHostMatch(pattern, host string) bool {
+	pattern = strings.ToLower(pattern)
+	host = strings.ToLower(host)
+	switch {
+	case pattern == "":
+		return true
+	case strings.HasPrefix(pattern, "*."):
+		return strings.HasSuffix(host, pattern[1:])
+	default:
+		return pattern == host
+	}
+}
@@ -0,0 +1,233 @@
+package enumerator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mysteriumnetwork/everssl/target"
+)
+
+const testKubeManifest = `
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: web
+  namespace: shop
+spec:
+  tls:
+  - hosts: [www.example.com, "*.apps.example.com"]
+    secretName: web-tls
+  - secretName: default-tls
+  rules:
+  - host: rule.example.com
+---
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: legacy
+spec:
+  tls:
+  - hosts: [legacy.example.com]
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: web-tls
+data:
+  tls.crt: bm90IGEgY2VydGlmaWNhdGU=
+---
+apiVersion: gateway.networking.k8s.io/v1
+kind: Gateway
+metadata:
+  name: gw
+spec:
+  listeners:
+  - name: exact
+    hostname: api.example.com
+    port: 8443
+    protocol: HTTPS
+  - name: wildcard
+    hostname: "*.example.org"
+    port: 443
+    protocol: HTTPS
+  - name: any
+    protocol: TLS
+  - name: plain
+    hostname: plain.example.com
+    protocol: HTTP
+`
+
+const testKubeRoutes = `{
+  "kind": "HTTPRouteList",
+  "items": [
+    {
+      "metadata": {"name": "shop", "namespace": "default"},
+      "spec": {
+        "parentRefs": [{"name": "gw", "sectionName": "wildcard"}],
+        "hostnames": ["shop.example.org", "*.eu.example.org", "shop.example.net"]
+      }
+    },
+    {
+      "metadata": {"name": "any", "namespace": "default"},
+      "spec": {
+        "parentRefs": [{"name": "gw", "sectionName": "any"}],
+        "hostnames": ["tls.example.net"]
+      }
+    },
+    {
+      "metadata": {"name": "other", "namespace": "other"},
+      "spec": {
+        "parentRefs": [{"name": "gw"}],
+        "hostnames": ["other.example.net"]
+      }
+    }
+  ]
+}`
+
+var testKubeTargets = []target.Target{
+	{Domain: "www.example.com", Port: DefaultPort, Source: "Kubernetes Ingress shop/web (secret shop/web-tls)"},
+	{Domain: "*.apps.example.com", Port: DefaultPort, Source: "Kubernetes Ingress shop/web (secret shop/web-tls)"},
+	{Domain: "rule.example.com", Port: DefaultPort, Source: "Kubernetes Ingress shop/web (secret shop/default-tls)"},
+	{Domain: "legacy.example.com", Port: DefaultPort, Source: "Kubernetes Ingress default/legacy"},
+	{Domain: "api.example.com", Port: "8443", Source: "Kubernetes Gateway default/gw listener exact"},
+	{Domain: "*.example.org", Port: "443", Source: "Kubernetes Gateway default/gw listener wildcard"},
+	{Domain: "shop.example.org", Port: "443", Source: "Kubernetes HTTPRoute default/shop via Kubernetes Gateway default/gw listener wildcard"},
+	{Domain: "*.eu.example.org", Port: "443", Source: "Kubernetes HTTPRoute default/shop via Kubernetes Gateway default/gw listener wildcard"},
+	{Domain: "tls.example.net", Port: DefaultPort, Source: "Kubernetes HTTPRoute default/any via Kubernetes Gateway default/gw listener any"},
+}
+
+func TestKubeEnumeratorManifests(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"objects.yaml": testKubeManifest,
+		"routes.json":  testKubeRoutes,
+		"README.md":    "not a manifest",
+	})
+
+	tests := []struct {
+		name string
+		zone string
+		want []target.Target
+	}{
+		{"all zones", AllZones, testKubeTargets},
+		{"zone", "example.org", testKubeTargets[5:8]},
+		{"zone without hosts", "example.edu", nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NewKubeEnumerator(dir).Enumerate(context.Background(), tc.zone, false)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertTargets(t, got, tc.want)
+		})
+	}
+}
+
+func TestKubeEnumeratorBadManifest(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"bad.yaml": "kind: Ingress\nspec: [\n"})
+	if _, err := NewKubeEnumerator(dir).Enumerate(context.Background(), AllZones, false); err == nil {
+		t.Error("error expected")
+	}
+}
+
+// startFakeKubeAPI serves objects of testKubeManifest and testKubeRoutes.
+// Ingresses are listed one per page.
+func startFakeKubeAPI(t *testing.T, withGateways bool) string {
+	t.Helper()
+
+	objects, err := readKubeManifests(writeFiles(t, map[string]string{
+		"objects.yaml": testKubeManifest,
+		"routes.json":  testKubeRoutes,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := func(kind string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer token" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			var items []kubeObject
+			for _, obj := range objects {
+				if obj.Kind == kind {
+					items = append(items, obj)
+				}
+			}
+
+			res := map[string]interface{}{"kind": kind + "List"}
+			if kind == "Ingress" {
+				i := 0
+				if r.URL.Query().Get("continue") != "" {
+					i = 1
+				}
+				if i+1 < len(items) {
+					res["metadata"] = map[string]string{"continue": "next"}
+				}
+				items = items[i : i+1]
+			}
+			res["items"] = items
+			json.NewEncoder(w).Encode(res)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/apis/networking.k8s.io/v1/ingresses", list("Ingress"))
+	if withGateways {
+		mux.HandleFunc("/apis/gateway.networking.k8s.io/v1/gateways", list("Gateway"))
+		mux.HandleFunc("/apis/gateway.networking.k8s.io/v1/httproutes", list("HTTPRoute"))
+	}
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestKubeEnumeratorAPI(t *testing.T) {
+	tests := []struct {
+		name     string
+		token    string
+		gateways bool
+		want     []target.Target
+		wantErr  bool
+	}{
+		{
+			name:     "all objects",
+			token:    "token",
+			gateways: true,
+			want:     testKubeTargets,
+		},
+		{
+			name:  "no Gateway API",
+			token: "token",
+			want:  testKubeTargets[:4],
+		},
+		{
+			name:     "unauthorized",
+			token:    "wrong",
+			gateways: true,
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e := NewKubeEnumerator(startFakeKubeAPI(t, tc.gateways)).SetToken(tc.token)
+			got, err := e.Enumerate(context.Background(), AllZones, false)
+			if tc.wantErr {
+				if err == nil {
+					t.Error("error expected")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertTargets(t, got, tc.want)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package enumerator
+
+import (
+	"context"
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/mysteriumnetwork/everssl/target"
+)
+
+// MultiEnumerator runs several enumerators concurrently and merges their
+// output. Targets found by more than one enumerator are reported once with
+// all sources listed.
+type MultiEnumerator struct {
+	enumerators    []Enumerator
+	tolerateErrors bool
+}
+
+func NewMultiEnumerator(enumerators ...Enumerator) *MultiEnumerator {
+	return &MultiEnumerator{
+		enumerators: enumerators,
+	}
+}
+
+// SetTolerateErrors makes failure of some enumerators non-fatal: targets of
+// remaining enumerators are returned along with the error.
+func (e *MultiEnumerator) SetTolerateErrors(tolerate bool) *MultiEnumerator {
+	e.tolerateErrors = tolerate
+	return e
+}
+
+func (e *MultiEnumerator) Enumerate(ctx context.Context, zone string, ipv6 bool) ([]target.Target, error) {
+	var wg sync.WaitGroup
+	results := make([][]target.Target, len(e.enumerators))
+	errors := make([]error, len(e.enumerators))
+
+	wg.Add(len(e.enumerators))
+	for idx, enumerator := range e.enumerators {
+		go func(idx int, enumerator Enumerator) {
+			defer wg.Done()
+
+			results[idx], errors[idx] = enumerator.Enumerate(ctx, zone, ipv6)
+		}(idx, enumerator)
+	}
+	wg.Wait()
+
+	var (
+		result  error
+		targets []target.Target
+	)
+	for idx, err := range errors {
+		if err != nil {
+			result = multierror.Append(result, err)
+		}
+		targets = append(targets, results[idx]...)
+	}
+
+	if IsFailure(result) && !e.tolerateErrors {
+		return nil, result
+	}
+
+	return target.Merge(targets), result
+}
@@ -0,0 +1,129 @@
+package enumerator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/mysteriumnetwork/everssl/target"
+)
+
+// stubEnumerator returns fixed targets and error
+type stubEnumerator struct {
+	targets []target.Target
+	err     error
+}
+
+func (e *stubEnumerator) Enumerate(ctx context.Context, zone string, ipv6 bool) ([]target.Target, error) {
+	return e.targets, e.err
+}
+
+func TestIsFailure(t *testing.T) {
+	zoneErr := &ZoneError{Zone: "example.com", Err: errors.New("forbidden")}
+	targetErr := &TargetError{Target: target.Target{Domain: "www.example.com"}, Err: errors.New("expired")}
+	failure := errors.New("unauthorized")
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"target error", targetErr, false},
+		{"zone error", zoneErr, false},
+		{"file error", &FileError{Path: "/etc/ssl/a.pem", Err: errors.New("malformed")}, false},
+		{"zone and target errors", multierror.Append(nil, zoneErr, targetErr), false},
+		{"cache fallback", &CacheFallbackError{Zone: "example.com", Err: failure}, false},
+		{"plain error", failure, true},
+		{"plain error among zone errors", multierror.Append(nil, zoneErr, failure), true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsFailure(tc.err); got != tc.want {
+				t.Errorf("IsFailure(%v) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMultiEnumerator(t *testing.T) {
+	good := &stubEnumerator{
+		targets: []target.Target{{Domain: "a.example.com", Source: "good"}},
+	}
+	partial := &stubEnumerator{
+		targets: []target.Target{{Domain: "b.example.com", Source: "partial"}},
+		err: multierror.Append(nil, &ZoneError{
+			Zone: "broken.example.com",
+			Err:  errors.New("forbidden"),
+		}),
+	}
+	failed := &stubEnumerator{
+		err: errors.New("unauthorized"),
+	}
+
+	tests := []struct {
+		name     string
+		enums    []Enumerator
+		tolerate bool
+		want     []string
+		wantErrs int
+	}{
+		{
+			name:  "success",
+			enums: []Enumerator{good, good},
+			want:  []string{"a.example.com"},
+		},
+		{
+			name:     "broken zone keeps targets",
+			enums:    []Enumerator{good, partial},
+			want:     []string{"a.example.com", "b.example.com"},
+			wantErrs: 1,
+		},
+		{
+			name:     "failure drops targets",
+			enums:    []Enumerator{good, partial, failed},
+			wantErrs: 2,
+		},
+		{
+			name:     "tolerated failure",
+			enums:    []Enumerator{good, partial, failed},
+			tolerate: true,
+			want:     []string{"a.example.com", "b.example.com"},
+			wantErrs: 2,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NewMultiEnumerator(tc.enums...).
+				SetTolerateErrors(tc.tolerate).
+				Enumerate(context.Background(), AllZones, false)
+
+			var domains []string
+			for _, tgt := range got {
+				domains = append(domains, tgt.Domain)
+			}
+			if len(domains) != len(tc.want) {
+				t.Fatalf("got targets %v, want %v", domains, tc.want)
+			}
+			for i := range domains {
+				if domains[i] != tc.want[i] {
+					t.Errorf("got targets %v, want %v", domains, tc.want)
+				}
+			}
+
+			errCount := 0
+			if merr, ok := err.(*multierror.Error); ok {
+				errCount = len(merr.Errors)
+			} else if err != nil {
+				errCount = 1
+			}
+			if errCount != tc.wantErrs {
+				t.Errorf("got %d errors (%v), want %d", errCount, err, tc.wantErrs)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package enumerator
+
+// PowerDNS Authoritative Server HTTP API
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const DefaultPowerDNSServerID = "localhost"
+
+type powerDNSProvider struct {
+	serverID string
+}
+
+type powerDNSZone struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	RRSets []struct {
+		Name    string `json:"name"`
+		Type    string `json:"type"`
+		Records []struct {
+			Content  string `json:"content"`
+			Disabled bool   `json:"disabled"`
+		} `json:"records"`
+	} `json:"rrsets"`
+}
+
+// NewPowerDNSEnumerator creates enumerator of zones served by PowerDNS
+// Authoritative Server with API at baseURL (e.g. "http://127.0.0.1:8081").
+// Empty serverID means "localhost".
+func NewPowerDNSEnumerator(baseURL, apiKey, serverID string) *DNSProviderEnumerator {
+	if serverID == "" {
+		serverID = DefaultPowerDNSServerID
+	}
+	return newDNSProviderEnumerator("PowerDNS", &powerDNSProvider{
+		serverID: serverID,
+	}, baseURL, http.Header{
+		"X-Api-Key": []string{apiKey},
+	})
+}
+
+func (p *powerDNSProvider) zonesPath() string {
+	return "/api/v1/servers/" + url.PathEscape(p.serverID) + "/zones"
+}
+
+func (p *powerDNSProvider) listZones(ctx context.Context, client *restClient) ([]string, error) {
+	var zones []powerDNSZone
+	if err := client.getJSON(ctx, p.zonesPath(), nil, &zones); err != nil {
+		return nil, err
+	}
+
+	res := make([]string, 0, len(zones))
+	for _, z := range zones {
+		res = append(res, strings.TrimSuffix(z.Name, "."))
+	}
+	return res, nil
+}
+
+func (p *powerDNSProvider) listRecords(ctx context.Context, client *restClient, zone string) ([]dnsRecord, error) {
+	// zone ID is its canonical name
+	var z powerDNSZone
+	err := client.getJSON(ctx, p.zonesPath()+"/"+url.PathEscape(zone+"."), nil, &z)
+	if err != nil {
+		return nil, err
+	}
+
+	var recs []dnsRecord
+	for _, rrset := range z.RRSets {
+		for _, r := range rrset.Records {
+			if r.Disabled {
+				continue
+			}
+			content := r.Content
+			switch rrset.Type {
+			case "MX":
+				content = strings.TrimSuffix(mxHost(content), ".")
+			case "CNAME", "NS":
+				content = strings.TrimSuffix(content, ".")
+			}
+			recs = append(recs, dnsRecord{
+				Name:    strings.TrimSuffix(rrset.Name, "."),
+				Type:    rrset.Type,
+				Content: content,
+			})
+		}
+	}
+	return recs, nil
+}
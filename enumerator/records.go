@@ -0,0 +1,94 @@
+package enumerator
+
+import (
+	"net"
+
+	"github.com/mysteriumnetwork/everssl/target"
+)
+
+// dnsRecord is a provider-independent representation of DNS record
+type dnsRecord struct {
+	Name    string
+	Type    string
+	Content string
+	Proxied bool
+}
+
+func isFakeOriginAddress(ip string) bool {
+	parsedIP := net.ParseIP(ip)
+	if parsedIP == nil {
+		return false
+	}
+	if parsedIP.Equal(CFWorkersBackendAddress) || parsedIP.Equal(PlaceholderAddress) {
+		return true
+	}
+	return false
+}
+
+// addRecordTargets selects targets worth checking from DNS records and adds
+// them to the targets set. Proxied records are checked both on origin and on
+// each of proxyPorts of the proxy. Targets are attributed to source.
+func addRecordTargets(targets map[target.Target]struct{}, unfilteredRecs []dnsRecord, ipv6, scanMX bool, proxyPorts []string, source string) {
+	var recs []dnsRecord
+	for _, rec := range unfilteredRecs {
+		switch rec.Type {
+		case "A", "CNAME", "NS":
+			recs = append(recs, rec)
+		case "AAAA":
+			if ipv6 {
+				recs = append(recs, rec)
+			}
+		case "MX":
+			if scanMX {
+				recs = append(recs, rec)
+			}
+		}
+	}
+
+	for _, record := range recs {
+		if record.Type == "MX" {
+			// Mail exchanger is checked by its own name with STARTTLS
+			targets[target.Target{
+				Domain:   record.Content,
+				Address:  "",
+				Port:     target.ProtocolSMTP.DefaultPort(),
+				Protocol: target.ProtocolSMTP,
+				Source:   source,
+			}] = struct{}{}
+			continue
+		}
+
+		checkOrigin := true
+		checkProxy := true
+		switch record.Type {
+		case "A", "AAAA":
+			checkOrigin = !(record.Proxied && isFakeOriginAddress(record.Content))
+			checkProxy = record.Proxied
+		case "CNAME":
+			checkProxy = record.Proxied
+			checkOrigin = true
+		}
+
+		// Add target for the domain name directly to origin server
+		if checkOrigin {
+			targets[target.Target{
+				Domain:  record.Name,
+				Address: record.Content,
+				Port:    DefaultPort,
+				Source:  source,
+			}] = struct{}{}
+		}
+
+		// Add target for the domain name via proxy
+		if checkProxy {
+			for _, port := range proxyPorts {
+				targets[target.Target{
+					Domain:  record.Name,
+					Address: "",
+					Port:    port,
+					Source:  source,
+				}] = struct{}{}
+			}
+		}
+	}
+}
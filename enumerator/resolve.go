@@ -0,0 +1,57 @@
+package enumerator
+
+import (
+	"context"
+	"log"
+	"net"
+
+	"github.com/mysteriumnetwork/everssl/target"
+)
+
+// Resolver looks up IP addresses of the host following CNAME chains.
+// *net.Resolver satisfies this interface.
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// resolveOrigins replaces each target with hostname origin address by
+// targets for every IP address the hostname resolves to. Targets which
+// can't be resolved are kept as is, so connection error surfaces later.
+func resolveOrigins(ctx context.Context, resolver Resolver, targets []target.Target, ipv6 bool) []target.Target {
+	cache := make(map[string][]string)
+	res := make([]target.Target, 0, len(targets))
+
+	for _, t := range targets {
+		if t.Address == "" || net.ParseIP(t.Address) != nil {
+			res = append(res, t)
+			continue
+		}
+
+		addrs, ok := cache[t.Address]
+		if !ok {
+			ipAddrs, err := resolver.LookupIPAddr(ctx, t.Address)
+			if err != nil {
+				log.Printf("unable to resolve origin %s of %s: %v", t.Address, t.Domain, err)
+			}
+			for _, ipAddr := range ipAddrs {
+				if ipAddr.IP.To4() == nil && !ipv6 {
+					continue
+				}
+				addrs = append(addrs, ipAddr.IP.String())
+			}
+			cache[t.Address] = addrs
+		}
+
+		if len(addrs) == 0 {
+			res = append(res, t)
+			continue
+		}
+		for _, addr := range addrs {
+			resolved := t
+			resolved.Address = addr
+			res = append(res, resolved)
+		}
+	}
+
+	return target.Merge(res)
+}
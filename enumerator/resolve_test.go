@@ -0,0 +1,93 @@
+package enumerator
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/mysteriumnetwork/everssl/target"
+)
+
+// fakeResolver resolves hosts from fixed table and counts lookups
+type fakeResolver struct {
+	hosts   map[string][]string
+	lookups map[string]int
+}
+
+func (r *fakeResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	r.lookups[host]++
+	addrs, ok := r.hosts[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	var res []net.IPAddr
+	for _, addr := range addrs {
+		res = append(res, net.IPAddr{IP: net.ParseIP(addr)})
+	}
+	return res, nil
+}
+
+func TestResolveOrigins(t *testing.T) {
+	hosts := map[string][]string{
+		"origin.example.net": {"192.0.2.1", "192.0.2.2", "2001:db8::1"},
+		"v6.example.net":     {"2001:db8::6"},
+	}
+	targets := []target.Target{
+		{Domain: "www.example.com", Address: "origin.example.net", Port: DefaultPort},
+		{Domain: "api.example.com", Address: "origin.example.net", Port: DefaultPort},
+		{Domain: "v6.example.com", Address: "v6.example.net", Port: DefaultPort},
+		{Domain: "gone.example.com", Address: "gone.example.net", Port: DefaultPort},
+		{Domain: "ip.example.com", Address: "192.0.2.9", Port: DefaultPort},
+		{Domain: "proxied.example.com", Port: DefaultPort},
+	}
+	unchanged := targets[2:]
+
+	tests := []struct {
+		name string
+		ipv6 bool
+		want []target.Target
+	}{
+		{
+			name: "IPv4",
+			want: append([]target.Target{
+				{Domain: "www.example.com", Address: "192.0.2.1", Port: DefaultPort},
+				{Domain: "www.example.com", Address: "192.0.2.2", Port: DefaultPort},
+				{Domain: "api.example.com", Address: "192.0.2.1", Port: DefaultPort},
+				{Domain: "api.example.com", Address: "192.0.2.2", Port: DefaultPort},
+			}, unchanged...),
+		},
+		{
+			name: "IPv6",
+			ipv6: true,
+			want: append([]target.Target{
+				{Domain: "www.example.com", Address: "192.0.2.1", Port: DefaultPort},
+				{Domain: "www.example.com", Address: "192.0.2.2", Port: DefaultPort},
+				{Domain: "www.example.com", Address: "2001:db8::1", Port: DefaultPort},
+				{Domain: "api.example.com", Address: "192.0.2.1", Port: DefaultPort},
+				{Domain: "api.example.com", Address: "192.0.2.2", Port: DefaultPort},
+				{Domain: "api.example.com", Address: "2001:db8::1", Port: DefaultPort},
+				{Domain: "v6.example.com", Address: "2001:db8::6", Port: DefaultPort},
+			}, unchanged[1:]...),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resolver := &fakeResolver{hosts: hosts, lookups: make(map[string]int)}
+			got := resolveOrigins(context.Background(), resolver, targets, tc.ipv6)
+			assertTargets(t, got, tc.want)
+
+			// every hostname is looked up once, unresolvable ones too
+			want := map[string]int{"origin.example.net": 1, "v6.example.net": 1, "gone.example.net": 1}
+			if len(resolver.lookups) != len(want) {
+				t.Errorf("got lookups %v, want %v", resolver.lookups, want)
+			}
+			for host, n := range want {
+				if resolver.lookups[host] != n {
+					t.Errorf("got lookups %v, want %v", resolver.lookups, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package enumerator
+
+import (
+	"context"
+	"strings"
+
+	"github.com/mysteriumnetwork/everssl/target"
+)
+
+// SyntheticWildcardLabel is used to test wildcard names when no sample
+// labels are configured. It's unlikely to collide with real records.
+const SyntheticWildcardLabel = "everssl-wildcard-probe"
+
+// WildcardEnumerator replaces targets with wildcard domains ("*.example.com")
+// by targets for sample hostnames covered by the wildcard. Original wildcard
+// is kept in target, so validator can check if certificate covers it.
+type WildcardEnumerator struct {
+	enumerator Enumerator
+	labels     []string
+}
+
+// NewWildcardEnumerator creates enumerator which expands wildcards of
+// targets returned by enumerator into hostnames with sample labels. Empty
+// labels list means synthetic label.
+func NewWildcardEnumerator(enumerator Enumerator, labels ...string) *WildcardEnumerator {
+	return &WildcardEnumerator{
+		enumerator: enumerator,
+		labels:     labels,
+	}
+}
+
+func (e *WildcardEnumerator) Enumerate(ctx context.Context, zone string, ipv6 bool) ([]target.Target, error) {
+	targets, err := e.enumerator.Enumerate(ctx, zone, ipv6)
+	return expandWildcards(targets, e.labels), err
+}
+
+// expandWildcards substitutes wildcard label with sample labels. Samples
+// which have explicit targets are skipped, because explicit records take
+// precedence over wildcard in DNS. Certificate files are not expanded.
+func expandWildcards(targets []target.Target, labels []string) []target.Target {
+	explicit := make(map[string]bool)
+	for _, t := range targets {
+		if !strings.HasPrefix(t.Domain, "*.") {
+			explicit[strings.ToLower(t.Domain)] = true
+		}
+	}
+
+	res := make([]target.Target, 0, len(targets))
+	for _, t := range targets {
+		if !strings.HasPrefix(t.Domain, "*.") || t.Protocol == target.ProtocolFile {
+			res = append(res, t)
+			continue
+		}
+
+		parent := t.Domain[2:]
+		var samples []string
+		for _, label := range labels {
+			sample := label + "." + parent
+			if !explicit[strings.ToLower(sample)] {
+				samples = append(samples, sample)
+			}
+		}
+		if len(samples) == 0 {
+			samples = []string{SyntheticWildcardLabel + "." + parent}
+		}
+
+		for _, sample := range samples {
+			expanded := t
+			expanded.Domain = sample
+			expanded.Wildcard = t.Domain
+			res = append(res, expanded)
+		}
+	}
+
+	return target.Merge(res)
+}
@@ -0,0 +1,144 @@
+package enumerator
+
+// Enumerator which reads RFC 1035 master files
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/miekg/dns"
+
+	"github.com/mysteriumnetwork/everssl/target"
+)
+
+type zoneFile struct {
+	origin string
+	path   string
+}
+
+type ZoneFileEnumerator struct {
+	files  []zoneFile
+	scanMX bool
+}
+
+// NewZoneFileEnumerator accepts zone file specifications in form
+// "[ORIGIN=]PATH". If origin is omitted, it is derived from the file name
+// by stripping "db." prefix and ".zone" or ".db" suffix. $ORIGIN directives
+// within zone file take precedence over initial origin. Files which fail to
+// parse during enumeration of all zones are reported as ZoneErrors of their
+// origins, targets of other files are still returned.
+func NewZoneFileEnumerator(specs ...string) *ZoneFileEnumerator {
+	files := make([]zoneFile, 0, len(specs))
+	for _, spec := range specs {
+		var origin, path string
+		if idx := strings.IndexByte(spec, '='); idx >= 0 {
+			origin, path = spec[:idx], spec[idx+1:]
+		} else {
+			path = spec
+			origin = filepath.Base(path)
+			origin = strings.TrimPrefix(origin, "db.")
+			origin = strings.TrimSuffix(origin, ".zone")
+			origin = strings.TrimSuffix(origin, ".db")
+		}
+		files = append(files, zoneFile{
+			origin: dns.Fqdn(origin),
+			path:   path,
+		})
+	}
+
+	return &ZoneFileEnumerator{
+		files: files,
+	}
+}
+
+// SetScanMX enables enumeration of mail exchangers as SMTP targets
+func (e *ZoneFileEnumerator) SetScanMX(scanMX bool) *ZoneFileEnumerator {
+	e.scanMX = scanMX
+	return e
+}
+
+func (e *ZoneFileEnumerator) Enumerate(ctx context.Context, zone string, ipv6 bool) ([]target.Target, error) {
+	targets := make(map[target.Target]struct{})
+
+	var resultErr error
+	for _, zf := range e.files {
+		if zone != AllZones && !strings.EqualFold(zf.origin, dns.Fqdn(zone)) {
+			continue
+		}
+
+		recs, err := parseZoneFile(zf)
+		if err != nil {
+			err = fmt.Errorf("unable to parse zone file %q: %w", zf.path, err)
+			if zone != AllZones {
+				return nil, err
+			}
+			resultErr = multierror.Append(resultErr, &ZoneError{
+				Zone: strings.TrimSuffix(zf.origin, "."),
+				Err:  err,
+			})
+			continue
+		}
+
+		addRecordTargets(targets, recs, ipv6, e.scanMX, []string{DefaultPort},
+			fmt.Sprintf("zone file %s", zf.path))
+	}
+
+	res := make([]target.Target, 0, len(targets))
+	for k := range targets {
+		res = append(res, k)
+	}
+
+	return target.Merge(res), resultErr
+}
+
+func parseZoneFile(zf zoneFile) ([]dnsRecord, error) {
+	f, err := os.Open(zf.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	zp := dns.NewZoneParser(f, zf.origin, zf.path)
+	zp.SetIncludeAllowed(true)
+
+	var recs []dnsRecord
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		if rec, ok := rrToRecord(rr); ok {
+			recs = append(recs, rec)
+		}
+	}
+	if err := zp.Err(); err != nil {
+		return nil, err
+	}
+
+	return recs, nil
+}
+
+// rrToRecord converts resource records relevant for target selection
+func rrToRecord(rr dns.RR) (dnsRecord, bool) {
+	rec := dnsRecord{
+		Name: strings.TrimSuffix(rr.Header().Name, "."),
+		Type: dns.TypeToString[rr.Header().Rrtype],
+	}
+
+	switch v := rr.(type) {
+	case *dns.A:
+		rec.Content = v.A.String()
+	case *dns.AAAA:
+		rec.Content = v.AAAA.String()
+	case *dns.CNAME:
+		rec.Content = strings.TrimSuffix(v.Target, ".")
+	case *dns.NS:
+		rec.Content = strings.TrimSuffix(v.Ns, ".")
+	case *dns.MX:
+		rec.Content = strings.TrimSuffix(v.Mx, ".")
+	default:
+		return rec, false
+	}
+
+	return rec, true
+}
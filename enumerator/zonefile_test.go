@@ -0,0 +1,222 @@
+package enumerator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/mysteriumnetwork/everssl/target"
+)
+
+const testZone = `$ORIGIN example.com.
+$TTL 3600
+@       IN SOA ns1 hostmaster 1 7200 900 1209600 300
+        IN NS  ns1
+        IN MX  10 mail
+ns1     IN A   192.0.2.53
+www     IN A   192.0.2.10
+www     IN AAAA 2001:db8::10
+api     IN CNAME www
+ext     IN CNAME edge.example.net.
+txt     IN TXT "not a target"
+$INCLUDE sub.inc
+`
+
+const testInclude = `$ORIGIN sub.example.com.
+app IN A 192.0.2.20
+`
+
+// writeFiles creates files with given names and contents in temporary
+// directory
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// sortTargets orders targets for comparison
+func sortTargets(targets []target.Target) []target.Target {
+	sort.Slice(targets, func(i, j int) bool {
+		a, b := targets[i], targets[j]
+		if a.Domain != b.Domain {
+			return a.Domain < b.Domain
+		}
+		if a.Address != b.Address {
+			return a.Address < b.Address
+		}
+		if a.Port != b.Port {
+			return a.Port < b.Port
+		}
+		return a.Protocol < b.Protocol
+	})
+	return targets
+}
+
+func assertTargets(t *testing.T, got, want []target.Target) {
+	t.Helper()
+	got = sortTargets(append([]target.Target{}, got...))
+	want = sortTargets(append([]target.Target{}, want...))
+	if len(got) == 0 && len(want) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected targets\n got: %+v\nwant: %+v", got, want)
+	}
+}
+
+func TestZoneFileEnumerator(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"db.example.com": testZone,
+		"sub.inc":        testInclude,
+	})
+	path := filepath.Join(dir, "db.example.com")
+	source := "zone file " + path
+
+	origin := func(domain, address string) target.Target {
+		return target.Target{Domain: domain, Address: address, Port: DefaultPort, Source: source}
+	}
+	proxy := func(domain string) target.Target {
+		return target.Target{Domain: domain, Port: DefaultPort, Source: source}
+	}
+	mx := target.Target{Domain: "mail.example.com", Port: "25", Protocol: target.ProtocolSMTP, Source: source}
+	base := []target.Target{
+		origin("example.com", "ns1.example.com"),
+		proxy("example.com"),
+		origin("ns1.example.com", "192.0.2.53"),
+		origin("www.example.com", "192.0.2.10"),
+		origin("api.example.com", "www.example.com"),
+		origin("ext.example.com", "edge.example.net"),
+		origin("app.sub.example.com", "192.0.2.20"),
+	}
+
+	tests := []struct {
+		name   string
+		spec   string
+		zone   string
+		ipv6   bool
+		scanMX bool
+		want   []target.Target
+	}{
+		{
+			name: "origin from file name",
+			spec: path,
+			zone: "example.com",
+			want: base,
+		},
+		{
+			name: "explicit origin",
+			spec: "example.com=" + path,
+			zone: "example.com.",
+			want: base,
+		},
+		{
+			name: "all zones",
+			spec: path,
+			zone: AllZones,
+			want: base,
+		},
+		{
+			name: "other zone",
+			spec: path,
+			zone: "example.org",
+			want: nil,
+		},
+		{
+			name: "IPv6",
+			spec: path,
+			zone: "example.com",
+			ipv6: true,
+			want: append([]target.Target{origin("www.example.com", "2001:db8::10")}, base...),
+		},
+		{
+			name:   "MX",
+			spec:   path,
+			zone:   "example.com",
+			scanMX: true,
+			want:   append([]target.Target{mx}, base...),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e := NewZoneFileEnumerator(tc.spec).SetScanMX(tc.scanMX)
+			got, err := e.Enumerate(context.Background(), tc.zone, tc.ipv6)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertTargets(t, got, tc.want)
+		})
+	}
+}
+
+func TestZoneFileEnumeratorErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"broken.zone": "$ORIGIN broken.example.\nwww IN A not-an-address\n",
+	})
+
+	tests := []struct {
+		name string
+		spec string
+	}{
+		{
+			name: "missing file",
+			spec: filepath.Join(dir, "missing.zone"),
+		},
+		{
+			name: "syntax error",
+			spec: "broken.example=" + filepath.Join(dir, "broken.zone"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewZoneFileEnumerator(tc.spec).Enumerate(context.Background(), AllZones, false)
+			if err == nil {
+				t.Fatal("error expected")
+			}
+		})
+	}
+}
+
+func TestZoneFileEnumeratorPartialFailure(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"db.example.com": "$ORIGIN example.com.\nwww IN A 192.0.2.10\n",
+		"broken.zone":    "$ORIGIN broken.example.\nwww IN A not-an-address\n",
+	})
+	good := filepath.Join(dir, "db.example.com")
+	e := NewZoneFileEnumerator(good, "broken.example="+filepath.Join(dir, "broken.zone"))
+
+	got, err := e.Enumerate(context.Background(), AllZones, false)
+	assertTargets(t, got, []target.Target{{
+		Domain:  "www.example.com",
+		Address: "192.0.2.10",
+		Port:    DefaultPort,
+		Source:  "zone file " + good,
+	}})
+	merr, ok := err.(*multierror.Error)
+	if !ok || len(merr.Errors) != 1 {
+		t.Fatalf("got error %v, want single zone error", err)
+	}
+	if zoneErr, ok := merr.Errors[0].(*ZoneError); !ok || zoneErr.Zone != "broken.example" {
+		t.Errorf("got error %#v, want zone error of broken.example", merr.Errors[0])
+	}
+	if IsFailure(err) {
+		t.Errorf("broken zone file is treated as failure: %v", err)
+	}
+
+	// failure of the only requested zone is not partial
+	_, err = e.Enumerate(context.Background(), "broken.example", false)
+	if !IsFailure(err) {
+		t.Errorf("got error %v, want failure", err)
+	}
+}
@@ -2,8 +2,11 @@ package reporter
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"strings"
 
+	"github.com/mysteriumnetwork/everssl/target"
 	"github.com/mysteriumnetwork/everssl/validator/result"
 )
 
@@ -24,12 +27,78 @@ func (r *LogReporter) SetVerbose(verbose bool) *LogReporter {
 
 func (r *LogReporter) Report(_ context.Context, results []result.ValidationResult) error {
 	for _, res := range results {
-		if res.Error != nil {
-			log.Printf("Problem with domain %s (Addr:%q): %v", res.Target.Domain, res.Target.Address, res.Error)
+		if res.Error != nil && res.Error.Kind() == result.EnumerationError {
+			log.Printf("Problem enumerating zone %s: %v", res.Target.Domain, res.Error)
+		} else if res.Error != nil && res.Error.Kind() == result.FallbackError {
+			log.Printf("Warning enumerating zone %s: %v", res.Target.Domain, res.Error)
+		} else if res.Error != nil {
+			prefix := "Problem with domain"
+			if res.Error.Kind().IsWarning() {
+				prefix = "Warning for domain"
+			}
+			log.Printf("%s %s (Addr:%q Port:%q Proto:%q)%s: %v", prefix,
+				res.Target.Domain, res.Target.Address, res.Target.EffectivePort(), res.Target.Protocol,
+				targetNotes(res.Target), res.Error)
 		} else if r.logOK {
-			log.Printf("Domain %s (Addr:%q): OK", res.Target.Domain, res.Target.Address)
+			log.Printf("Domain %s (Addr:%q Port:%q Proto:%q)%s: OK%s%s",
+				res.Target.Domain, res.Target.Address, res.Target.EffectivePort(), res.Target.Protocol,
+				targetNotes(res.Target), trustStoreInfo(res.TrustStore), stapleInfo(res.Staple))
 		}
 	}
 
 	return nil
 }
+
+// targetNotes describes where target came from
+func targetNotes(t target.Target) string {
+	return wildcardInfo(t) + foundVia(t) + lbInfo(t)
+}
+
+func wildcardInfo(t target.Target) string {
+	if t.Wildcard == "" {
+		return ""
+	}
+	return " for wildcard " + t.Wildcard
+}
+
+func foundVia(t target.Target) string {
+	if t.Source == "" {
+		return ""
+	}
+	return " found via " + t.Source
+}
+
+func lbInfo(t target.Target) string {
+	var members []string
+	for _, lb := range t.LoadBalancer.Members() {
+		if lb.Pool == "" {
+			members = append(members, fmt.Sprintf("load balancer %s", lb.Name))
+		} else {
+			members = append(members, fmt.Sprintf("load balancer %s, pool %s, origin %s", lb.Name, lb.Pool, lb.Origin))
+		}
+	}
+	if len(members) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(members, "; ") + ")"
+}
+
+func trustStoreInfo(store string) string {
+	if store == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (verified against %s trust store)", store)
+}
+
+func stapleInfo(staple *result.Staple) string {
+	switch {
+	case staple == nil:
+		return ""
+	case !staple.Present:
+		return " (no OCSP staple)"
+	case staple.NextUpdate.IsZero():
+		return fmt.Sprintf(" (OCSP staple %s)", staple.Status)
+	default:
+		return fmt.Sprintf(" (OCSP staple %s until %v)", staple.Status, staple.NextUpdate)
+	}
+}
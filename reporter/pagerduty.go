@@ -3,11 +3,13 @@ package reporter
 import (
 	"context"
 	"fmt"
+	"net"
 	"time"
 
 	"github.com/PagerDuty/go-pagerduty"
 	"github.com/hashicorp/go-multierror"
 
+	"github.com/mysteriumnetwork/everssl/target"
 	"github.com/mysteriumnetwork/everssl/validator/result"
 )
 
@@ -32,12 +34,13 @@ func (r *PagerDutyReporter) Report(ctx context.Context, results []result.Validat
 		event := pagerduty.V2Event{
 			RoutingKey: r.routingKey,
 			Action:     "trigger",
-			DedupKey:   fmt.Sprintf("%s/%s", res.Target.Domain, res.Target.Address),
+			DedupKey:   dedupKey(res),
 			Payload: &pagerduty.V2Payload{
 				Summary:   res.Error.Error(),
-				Source:    fmt.Sprintf("https://%s/", res.Target.Domain),
+				Source:    source(res),
 				Severity:  "warning",
 				Timestamp: time.Now().UTC().Format(time.RFC3339),
+				Details:   details(res),
 			},
 		}
 
@@ -49,3 +52,68 @@ func (r *PagerDutyReporter) Report(ctx context.Context, results []result.Validat
 
 	return resultErr
 }
+
+func dedupKey(res result.ValidationResult) string {
+	t := res.Target
+	switch res.Error.Kind() {
+	case result.EnumerationError:
+		return fmt.Sprintf("enumeration/%s", t.Domain)
+	case result.FallbackError:
+		return fmt.Sprintf("enumeration-cache/%s", t.Domain)
+	}
+	// targets which existed before ports and protocols were introduced keep
+	// their keys, so open incidents are resolved by the same key
+	if t.Protocol == target.ProtocolTLS && t.EffectivePort() == t.Protocol.DefaultPort() {
+		return fmt.Sprintf("%s/%s", t.Domain, t.Address)
+	}
+	return fmt.Sprintf("%s/%s/%s/%s", t.Domain, t.Address, t.EffectivePort(), t.Protocol)
+}
+
+func source(res result.ValidationResult) string {
+	t := res.Target
+	if res.Error.Kind().IsZoneProblem() {
+		return t.Domain
+	}
+
+	if t.Protocol == target.ProtocolFile {
+		return "file://" + t.Address
+	}
+
+	scheme := string(t.Protocol)
+	if t.Protocol == target.ProtocolTLS {
+		scheme = "https"
+	}
+	host := t.Domain
+	if t.EffectivePort() != t.Protocol.DefaultPort() {
+		host = net.JoinHostPort(t.Domain, t.EffectivePort())
+	}
+	return fmt.Sprintf("%s://%s/", scheme, host)
+}
+
+func details(res result.ValidationResult) map[string]string {
+	t := res.Target
+	d := map[string]string{
+		"domain":    t.Domain,
+		"address":   t.Address,
+		"port":      t.EffectivePort(),
+		"protocol":  string(t.Protocol),
+		"found_via": t.Source,
+		"lb":        t.LoadBalancer.Name,
+		"lb_pool":   t.LoadBalancer.Pool,
+		"lb_origin": t.LoadBalancer.Origin,
+		"wildcard":  t.Wildcard,
+	}
+	if res.TrustStore != "" {
+		d["trust_store"] = res.TrustStore
+	}
+	if staple := res.Staple; staple != nil {
+		d["ocsp_staple"] = "missing"
+		if staple.Present {
+			d["ocsp_staple"] = staple.Status
+		}
+		if !staple.NextUpdate.IsZero() {
+			d["ocsp_staple_next_update"] = staple.NextUpdate.UTC().Format(time.RFC3339)
+		}
+	}
+	return d
+}
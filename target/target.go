@@ -1,6 +1,219 @@
 package target
 
+import (
+	"fmt"
+	"strings"
+)
+
+// Protocol selects the exchange performed on a connection before the TLS
+// handshake. Empty value means TLS is spoken right away.
+type Protocol string
+
+const (
+	ProtocolTLS  = Protocol("")
+	ProtocolSMTP = Protocol("smtp")
+	ProtocolIMAP = Protocol("imap")
+	ProtocolPOP3 = Protocol("pop3")
+	ProtocolFTP  = Protocol("ftp")
+	// ProtocolFile means certificate is read from local file at Address
+	// instead of connecting anywhere
+	ProtocolFile = Protocol("file")
+)
+
+// ParseProtocol converts protocol name into Protocol. Empty string, "tls" and
+// "https" mean TLS without any preceding exchange.
+func ParseProtocol(name string) (Protocol, error) {
+	switch p := Protocol(strings.ToLower(name)); p {
+	case "tls", "https":
+		return ProtocolTLS, nil
+	case ProtocolTLS, ProtocolSMTP, ProtocolIMAP, ProtocolPOP3, ProtocolFTP, ProtocolFile:
+		return p, nil
+	default:
+		return ProtocolTLS, fmt.Errorf("unknown protocol %q", name)
+	}
+}
+
+// DefaultPort returns well-known port used with STARTTLS-like upgrade of
+// the protocol. Files have no port.
+func (p Protocol) DefaultPort() string {
+	switch p {
+	case ProtocolFile:
+		return ""
+	case ProtocolSMTP:
+		return "25"
+	case ProtocolIMAP:
+		return "143"
+	case ProtocolPOP3:
+		return "110"
+	case ProtocolFTP:
+		return "21"
+	default:
+		return "443"
+	}
+}
+
 type Target struct {
 	Domain  string
 	Address string
+	// Port to connect to. Empty value means default port of the Protocol.
+	Port     string
+	Protocol Protocol
+	// Source describes where target was found. Several sources are
+	// separated with SourceSeparator.
+	Source string
+	// LoadBalancer is set for targets which belong to load balancer. Merged
+	// targets list all their load balancer, pool and origin combinations,
+	// see LoadBalancer.Members.
+	LoadBalancer LoadBalancer
+	// Wildcard is the wildcard name ("*.example.com") which Domain was
+	// derived from. Certificate has to cover the wildcard itself.
+	Wildcard string
+	// TrustStore names trust store to verify certificate chain against.
+	// Empty value means trust store is chosen by validator.
+	TrustStore string
+}
+
+// LoadBalancer identifies load balancer, its pool and origin. Several
+// combinations are stored as SourceSeparator-separated lists of the same
+// length, so i-th name, pool and origin belong together. Pool and Origin
+// are left empty if none of combinations has them.
+type LoadBalancer struct {
+	Name   string
+	Pool   string
+	Origin string
+}
+
+// Members returns load balancer, pool and origin combinations listed in lb
+func (lb LoadBalancer) Members() []LoadBalancer {
+	if lb.Name == "" {
+		return nil
+	}
+	names := strings.Split(lb.Name, SourceSeparator)
+	pools := splitMembers(lb.Pool, len(names))
+	origins := splitMembers(lb.Origin, len(names))
+
+	res := make([]LoadBalancer, len(names))
+	for i := range names {
+		res[i] = LoadBalancer{
+			Name:   names[i],
+			Pool:   pools[i],
+			Origin: origins[i],
+		}
+	}
+	return res
+}
+
+func splitMembers(joined string, n int) []string {
+	res := make([]string, n)
+	if joined != "" {
+		copy(res, strings.Split(joined, SourceSeparator))
+	}
+	return res
+}
+
+// joinLoadBalancers lists combinations in single LoadBalancer
+func joinLoadBalancers(members []LoadBalancer) LoadBalancer {
+	var (
+		names, pools, origins []string
+		hasPool, hasOrigin    bool
+	)
+	for _, m := range members {
+		names = append(names, m.Name)
+		pools = append(pools, m.Pool)
+		origins = append(origins, m.Origin)
+		hasPool = hasPool || m.Pool != ""
+		hasOrigin = hasOrigin || m.Origin != ""
+	}
+
+	res := LoadBalancer{
+		Name: strings.Join(names, SourceSeparator),
+	}
+	if hasPool {
+		res.Pool = strings.Join(pools, SourceSeparator)
+	}
+	if hasOrigin {
+		res.Origin = strings.Join(origins, SourceSeparator)
+	}
+	return res
+}
+
+const SourceSeparator = ", "
+
+// EffectivePort returns port which will be used to connect to the target.
+func (t Target) EffectivePort() string {
+	if t.Port != "" {
+		return t.Port
+	}
+	return t.Protocol.DefaultPort()
+}
+
+// Merge deduplicates targets which differ only by Source, LoadBalancer or
+// by explicit default port. Sources and load balancer combinations of
+// duplicates are joined. Order of first occurrences is preserved.
+func Merge(targets []Target) []Target {
+	var res []Target
+	sources := make(map[int][]string)
+	lbs := make(map[int][]LoadBalancer)
+	index := make(map[Target]int)
+
+	for _, t := range targets {
+		key := t
+		key.Source = ""
+		key.LoadBalancer = LoadBalancer{}
+		key.Port = t.EffectivePort()
+		idx, ok := index[key]
+		if !ok {
+			idx = len(res)
+			index[key] = idx
+			res = append(res, key)
+		}
+		sources[idx] = appendUnique(sources[idx], t.Source)
+		lbs[idx] = appendUniqueMembers(lbs[idx], t.LoadBalancer)
+	}
+
+	for idx := range res {
+		res[idx].Source = strings.Join(sources[idx], SourceSeparator)
+		res[idx].LoadBalancer = joinLoadBalancers(lbs[idx])
+	}
+
+	return res
+}
+
+// appendUnique appends elements of SourceSeparator-separated list which are
+// not in list yet
+func appendUnique(list []string, joined string) []string {
+	if joined == "" {
+		return list
+	}
+	for _, elem := range strings.Split(joined, SourceSeparator) {
+		known := false
+		for _, s := range list {
+			if s == elem {
+				known = true
+				break
+			}
+		}
+		if !known {
+			list = append(list, elem)
+		}
+	}
+	return list
+}
+
+// appendUniqueMembers appends load balancer combinations of lb which are not
+// in list yet
+func appendUniqueMembers(list []LoadBalancer, lb LoadBalancer) []LoadBalancer {
+	for _, m := range lb.Members() {
+		known := false
+		for _, l := range list {
+			if l == m {
+				known = true
+				break
+			}
+		}
+		if !known {
+			list = append(list, m)
+		}
+	}
+	return list
 }
@@ -0,0 +1,130 @@
+package target
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets []Target
+		want    []Target
+	}{
+		{
+			name: "sources are joined",
+			targets: []Target{
+				{Domain: "a.example.com", Port: "443", Source: "zone file"},
+				{Domain: "b.example.com", Port: "443", Source: "zone file"},
+				{Domain: "a.example.com", Port: "443", Source: "CT log, zone file"},
+			},
+			want: []Target{
+				{Domain: "a.example.com", Port: "443", Source: "zone file, CT log"},
+				{Domain: "b.example.com", Port: "443", Source: "zone file"},
+			},
+		},
+		{
+			name: "default port",
+			targets: []Target{
+				{Domain: "a.example.com", Source: "inventory"},
+				{Domain: "a.example.com", Port: "443", Source: "Cloudflare"},
+				{Domain: "mx.example.com", Protocol: ProtocolSMTP},
+				{Domain: "mx.example.com", Port: "25", Protocol: ProtocolSMTP},
+				{Domain: "mx.example.com", Port: "443"},
+			},
+			want: []Target{
+				{Domain: "a.example.com", Port: "443", Source: "inventory, Cloudflare"},
+				{Domain: "mx.example.com", Port: "25", Protocol: ProtocolSMTP},
+				{Domain: "mx.example.com", Port: "443"},
+			},
+		},
+		{
+			name: "same origin in several pools",
+			targets: []Target{
+				{Domain: "lb.example.com", Address: "192.0.2.1", Port: "443",
+					LoadBalancer: LoadBalancer{Name: "lb.example.com", Pool: "eu", Origin: "web1"}},
+				{Domain: "lb.example.com", Address: "192.0.2.1", Port: "443",
+					LoadBalancer: LoadBalancer{Name: "lb.example.com", Pool: "us", Origin: "web1"}},
+				{Domain: "lb.example.com", Port: "443",
+					LoadBalancer: LoadBalancer{Name: "lb.example.com"}},
+			},
+			want: []Target{
+				{Domain: "lb.example.com", Address: "192.0.2.1", Port: "443",
+					LoadBalancer: LoadBalancer{Name: "lb.example.com, lb.example.com", Pool: "eu, us", Origin: "web1, web1"}},
+				{Domain: "lb.example.com", Port: "443",
+					LoadBalancer: LoadBalancer{Name: "lb.example.com"}},
+			},
+		},
+		{
+			name: "combinations are kept together",
+			targets: []Target{
+				{Domain: "a.example.com", Address: "192.0.2.1", Port: "443",
+					LoadBalancer: LoadBalancer{Name: "a.example.com, b.example.com", Pool: "eu, us", Origin: "web1, web2"}},
+				{Domain: "a.example.com", Address: "192.0.2.1", Port: "443",
+					LoadBalancer: LoadBalancer{Name: "b.example.com", Pool: "us", Origin: "web2"}},
+				{Domain: "a.example.com", Address: "192.0.2.1", Port: "443",
+					LoadBalancer: LoadBalancer{Name: "a.example.com", Pool: "us", Origin: "web1"}},
+				{Domain: "a.example.com", Address: "192.0.2.1", Port: "443"},
+			},
+			want: []Target{
+				{Domain: "a.example.com", Address: "192.0.2.1", Port: "443",
+					LoadBalancer: LoadBalancer{
+						Name:   "a.example.com, b.example.com, a.example.com",
+						Pool:   "eu, us, us",
+						Origin: "web1, web2, web1",
+					}},
+			},
+		},
+		{
+			name: "file targets have no port",
+			targets: []Target{
+				{Domain: "a.example.com", Address: "/etc/ssl/a.pem", Protocol: ProtocolFile},
+				{Domain: "a.example.com", Address: "/etc/ssl/a.pem", Protocol: ProtocolFile},
+			},
+			want: []Target{
+				{Domain: "a.example.com", Address: "/etc/ssl/a.pem", Protocol: ProtocolFile},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Merge(tc.targets); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("unexpected result\n got: %+v\nwant: %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadBalancerMembers(t *testing.T) {
+	tests := []struct {
+		name string
+		lb   LoadBalancer
+		want []LoadBalancer
+	}{
+		{
+			name: "none",
+		},
+		{
+			name: "load balancers without pools",
+			lb:   LoadBalancer{Name: "a.example.com, b.example.com"},
+			want: []LoadBalancer{{Name: "a.example.com"}, {Name: "b.example.com"}},
+		},
+		{
+			name: "combinations",
+			lb:   LoadBalancer{Name: "a.example.com, a.example.com", Pool: "eu, us", Origin: "web1, web1"},
+			want: []LoadBalancer{
+				{Name: "a.example.com", Pool: "eu", Origin: "web1"},
+				{Name: "a.example.com", Pool: "us", Origin: "web1"},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.lb.Members(); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
@@ -6,11 +6,13 @@ import (
 	"crypto/x509"
 	"fmt"
 	"net"
+	"strings"
 	"sync"
 	"time"
 
 	"golang.org/x/time/rate"
 
+	"github.com/mysteriumnetwork/everssl/certfile"
 	fixedDialer "github.com/mysteriumnetwork/everssl/dialer"
 	"github.com/mysteriumnetwork/everssl/target"
 	"github.com/mysteriumnetwork/everssl/validator/result"
@@ -22,6 +24,13 @@ type ConcurrentValidator struct {
 	singleTimeout      time.Duration
 	retries            int
 	verify             bool
+	pkcs12Password     string
+	revocation         *revocationChecker
+	expectStapling     bool
+	stapleMinValidity  time.Duration
+	clockSkew          time.Duration
+	trustStores        map[string]*TrustStore
+	zoneTrustStores    map[string]string
 }
 
 func NewConcurrentValidator(expirationTreshold, rateEvery, singleTimeout time.Duration, retries int, verify bool) *ConcurrentValidator {
@@ -32,9 +41,57 @@ func NewConcurrentValidator(expirationTreshold, rateEvery, singleTimeout time.Du
 		verify:             verify,
 		singleTimeout:      singleTimeout,
 		retries:            retries,
+		trustStores:        make(map[string]*TrustStore),
+		zoneTrustStores:    make(map[string]string),
 	}
 }
 
+// SetPKCS12Password sets password of PKCS#12 certificate files
+func (v *ConcurrentValidator) SetPKCS12Password(password string) *ConcurrentValidator {
+	v.pkcs12Password = password
+	return v
+}
+
+// SetCheckRevocation enables OCSP and CRL revocation checks of leaf
+// certificates. Requests to OCSP responders and CRL distribution points
+// are rate limited along with handshakes.
+func (v *ConcurrentValidator) SetCheckRevocation(check bool) *ConcurrentValidator {
+	v.revocation = nil
+	if check {
+		v.revocation = newRevocationChecker(v.singleTimeout, v.limiter)
+	}
+	return v
+}
+
+// SetExpectStapling makes missing, bad or stale OCSP staple a problem.
+// Staple is stale if it expires sooner than minValidity.
+func (v *ConcurrentValidator) SetExpectStapling(expect bool, minValidity time.Duration) *ConcurrentValidator {
+	v.expectStapling = expect
+	v.stapleMinValidity = minValidity
+	return v
+}
+
+// SetClockSkew sets tolerance of certificates which become valid a bit
+// later than local clock says
+func (v *ConcurrentValidator) SetClockSkew(skew time.Duration) *ConcurrentValidator {
+	v.clockSkew = skew
+	return v
+}
+
+// AddTrustStore makes trust store available to targets by its name. Store
+// named DefaultTrustStore is used for targets without assigned store.
+func (v *ConcurrentValidator) AddTrustStore(store *TrustStore) *ConcurrentValidator {
+	v.trustStores[store.name] = store
+	return v
+}
+
+// SetZoneTrustStore assigns named trust store to targets in zone which
+// don't name trust store themselves
+func (v *ConcurrentValidator) SetZoneTrustStore(zone, name string) *ConcurrentValidator {
+	v.zoneTrustStores[strings.ToLower(strings.TrimSuffix(zone, "."))] = name
+	return v
+}
+
 func (v *ConcurrentValidator) Validate(ctx context.Context, targets []target.Target) ([]result.ValidationResult, error) {
 	var wg sync.WaitGroup
 	results := make([]result.ValidationResult, len(targets))
@@ -44,10 +101,11 @@ func (v *ConcurrentValidator) Validate(ctx context.Context, targets []target.Tar
 		go func(idx int, t target.Target) {
 			defer wg.Done()
 
-			err := v.validateSingle(ctx, t)
-			results[idx] = result.ValidationResult{
-				Target: t,
-				Error:  err,
+			results[idx].Target = t
+			if t.Protocol == target.ProtocolFile {
+				results[idx].Error = v.validateFile(ctx, t, &results[idx])
+			} else {
+				results[idx].Error = v.validateSingle(ctx, t, &results[idx])
 			}
 		}(idx, t)
 	}
@@ -56,12 +114,14 @@ func (v *ConcurrentValidator) Validate(ctx context.Context, targets []target.Tar
 	return results, nil
 }
 
-func (v *ConcurrentValidator) validateSingle(ctx context.Context, target target.Target) result.ValidationError {
+// validateSingle connects to the target and checks served certificate.
+// Details of successful handshake are recorded in res.
+func (v *ConcurrentValidator) validateSingle(ctx context.Context, target target.Target, res *result.ValidationResult) result.ValidationError {
 	var (
 		conn net.Conn
 		err  error
 	)
-	dialer := fixedDialer.NewFixedDialer(target.Address, "", &net.Dialer{})
+	dialer := fixedDialer.NewFixedDialer(target.Address, target.Port, &net.Dialer{})
 
 	for i := 0; i < v.retries; i++ {
 		err = v.limiter.Wait(ctx)
@@ -72,7 +132,7 @@ func (v *ConcurrentValidator) validateSingle(ctx context.Context, target target.
 		ctx1, cl := context.WithTimeout(ctx, v.singleTimeout)
 		defer cl()
 
-		conn, err = dialer.DialContext(ctx1, "tcp", net.JoinHostPort(target.Domain, "443"))
+		conn, err = dialer.DialContext(ctx1, "tcp", net.JoinHostPort(target.Domain, target.Protocol.DefaultPort()))
 		if err != nil {
 			continue
 		}
@@ -83,25 +143,35 @@ func (v *ConcurrentValidator) validateSingle(ctx context.Context, target target.
 		return newValidationError(result.ConnectionError, fmt.Errorf("all attempts failed. last error: %w", err))
 	}
 
-	var notAfter time.Time
+	startCtx, startCl := context.WithTimeout(ctx, v.singleTimeout)
+	defer startCl()
+	if deadline, ok := startCtx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	err = startTLS(conn, target.Protocol)
+	if err != nil {
+		return newValidationError(result.HandshakeError, fmt.Errorf("%s STARTTLS negotiation failed: %w", target.Protocol, err))
+	}
+	conn.SetDeadline(time.Time{})
+
+	store, verr := v.trustStoreFor(target)
+	if verr != nil && v.verify {
+		return verr
+	}
+
+	var chains [][]*x509.Certificate
 
 	tlsConn := tls.Client(conn, &tls.Config{
 		ServerName:         target.Domain,
 		InsecureSkipVerify: true,
 		VerifyConnection: func(cs tls.ConnectionState) error {
-			notAfter = cs.PeerCertificates[0].NotAfter
-			if v.verify {
-				opts := x509.VerifyOptions{
-					DNSName:       cs.ServerName,
-					Intermediates: x509.NewCertPool(),
-				}
-				for _, cert := range cs.PeerCertificates[1:] {
-					opts.Intermediates.AddCert(cert)
-				}
-				_, err := cs.PeerCertificates[0].Verify(opts)
-				if err != nil {
-					return newValidationError(result.VerificationError, err)
-				}
+			if err := v.checkNotBefore(cs.PeerCertificates); err != nil {
+				return err
+			}
+			var err result.ValidationError
+			chains, err = v.verifyChain(store, cs.ServerName, target.Wildcard, cs.PeerCertificates)
+			if err != nil {
+				return err
 			}
 			return nil
 		},
@@ -120,15 +190,223 @@ func (v *ConcurrentValidator) validateSingle(ctx context.Context, target target.
 		}
 	}
 
-	now := time.Now().Truncate(0)
-	remainingDuration := notAfter.Sub(now)
-	if remainingDuration < v.expirationTreshold {
-		return newValidationError(result.ExpirationError, fmt.Errorf("leaf certificate will be valid only until %v", notAfter))
+	cs := tlsConn.ConnectionState()
+	if chains != nil {
+		res.TrustStore = store.verifiedBy(chains)
+	}
+	// issuer may come from private trust store rather than served chain
+	certs := withVerifiedChain(cs.PeerCertificates, chains)
+	res.Staple = inspectStaple(certs, cs.OCSPResponse)
+
+	if v.revocation != nil {
+		if err := v.revocation.check(ctx, certs, cs.OCSPResponse, v.clockSkew); err != nil {
+			return err
+		}
+	}
+
+	if err := v.checkExpiration(cs.PeerCertificates, chains); err != nil {
+		return err
 	}
 
+	if v.expectStapling {
+		return v.checkStaple(res.Staple)
+	}
 	return nil
 }
 
+// validateFile checks certificate chain loaded from local file
+func (v *ConcurrentValidator) validateFile(ctx context.Context, t target.Target, res *result.ValidationResult) result.ValidationError {
+	bundle, err := certfile.Load(t.Address, v.pkcs12Password)
+	if err != nil {
+		return newValidationError(result.LoadError, fmt.Errorf("unable to load certificate: %w", err))
+	}
+
+	// there is no SNI for files, so wildcard domain is checked as is
+	serverName, wildcard := t.Domain, t.Wildcard
+	if strings.HasPrefix(serverName, "*") {
+		serverName, wildcard = "", serverName
+	}
+	if err := v.checkNotBefore(bundle.Certificates); err != nil {
+		return err
+	}
+	store, verr := v.trustStoreFor(t)
+	if verr != nil && v.verify {
+		return verr
+	}
+	chains, verr := v.verifyChain(store, serverName, wildcard, bundle.Certificates)
+	if verr != nil {
+		return verr
+	}
+	if chains != nil {
+		res.TrustStore = store.verifiedBy(chains)
+	}
+
+	if v.revocation != nil {
+		if err := v.revocation.check(ctx, withVerifiedChain(bundle.Certificates, chains), nil, v.clockSkew); err != nil {
+			return err
+		}
+	}
+
+	return v.checkExpiration(bundle.Certificates, chains)
+}
+
+// verifyChain verifies certificate chain presented for serverName against
+// trust store, nil store means system roots. Leaf certificate goes first. Non-empty wildcard has to be covered by leaf
+// certificate as well. Verified chains are returned on success.
+func (v *ConcurrentValidator) verifyChain(store *TrustStore, serverName, wildcard string, certs []*x509.Certificate) ([][]*x509.Certificate, result.ValidationError) {
+	if !v.verify {
+		return nil, nil
+	}
+
+	opts := x509.VerifyOptions{
+		DNSName:       serverName,
+		Intermediates: x509.NewCertPool(),
+		CurrentTime:   v.verificationTime(certs),
+	}
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	if store != nil {
+		opts.Roots = store.pool
+	}
+	chains, err := certs[0].Verify(opts)
+	if err != nil {
+		if store != nil {
+			err = fmt.Errorf("verification against trust store %s failed: %w", store.name, err)
+		}
+		return nil, newValidationError(result.VerificationError, err)
+	}
+
+	// wildcard name only matches identical wildcard in certificate
+	if wildcard != "" {
+		if err := certs[0].VerifyHostname(wildcard); err != nil {
+			return nil, newValidationError(result.VerificationError,
+				fmt.Errorf("certificate doesn't cover wildcard %s: %w", wildcard, err))
+		}
+	}
+	return chains, nil
+}
+
+// checkNotBefore checks if served certificates are valid already, allowing
+// for clock skew. It's done regardless of chain verification.
+func (v *ConcurrentValidator) checkNotBefore(certs []*x509.Certificate) result.ValidationError {
+	now := time.Now().Truncate(0)
+	for i, cert := range certs {
+		if cert.NotBefore.Sub(now) > v.clockSkew {
+			return newValidationError(result.NotYetValidError,
+				fmt.Errorf("%s is not valid before %v", describeCert("served", i, cert), cert.NotBefore))
+		}
+	}
+	return nil
+}
+
+// verificationTime moves verification time forward to the latest start of
+// validity of served certificates, so chain verification tolerates the same
+// clock skew as checkNotBefore
+func (v *ConcurrentValidator) verificationTime(certs []*x509.Certificate) time.Time {
+	now := time.Now()
+	res := now
+	for _, cert := range certs {
+		if cert.NotBefore.After(res) && cert.NotBefore.Sub(now) <= v.clockSkew {
+			res = cert.NotBefore
+		}
+	}
+	return res
+}
+
+// checkExpiration checks every served certificate and every certificate
+// of the longest living verified chain against expiration treshold. The
+// soonest expiring certificate is reported.
+func (v *ConcurrentValidator) checkExpiration(served []*x509.Certificate, verified [][]*x509.Certificate) result.ValidationError {
+	var (
+		expiring *x509.Certificate
+		desc     string
+	)
+	check := func(chain string, pos int, cert *x509.Certificate) {
+		if expiring != nil && !cert.NotAfter.Before(expiring.NotAfter) {
+			return
+		}
+		expiring = cert
+		desc = describeCert(chain, pos, cert)
+	}
+
+	for i, cert := range served {
+		check("served", i, cert)
+	}
+	for i, cert := range longestLivingChain(verified) {
+		if !containsCert(served, cert) {
+			check("verified", i, cert)
+		}
+	}
+
+	now := time.Now().Truncate(0)
+	if expiring == nil || expiring.NotAfter.Sub(now) >= v.expirationTreshold {
+		return nil
+	}
+	return newValidationError(result.ExpirationError,
+		fmt.Errorf("%s will be valid only until %v", desc, expiring.NotAfter))
+}
+
+// describeCert names certificate by its position in the chain, subject and
+// issuer
+func describeCert(chain string, pos int, cert *x509.Certificate) string {
+	desc := fmt.Sprintf("certificate #%d of %s chain", pos, chain)
+	if pos == 0 {
+		desc = "leaf certificate"
+	}
+	return fmt.Sprintf("%s (subject %q, issuer %q)", desc, cert.Subject, cert.Issuer)
+}
+
+// longestLivingChain picks verified chain which stays valid longest, as
+// clients are free to build any of them
+func longestLivingChain(chains [][]*x509.Certificate) []*x509.Certificate {
+	var (
+		best    []*x509.Certificate
+		bestEnd time.Time
+	)
+	for _, chain := range chains {
+		end := chainNotAfter(chain)
+		if best == nil || end.After(bestEnd) {
+			best, bestEnd = chain, end
+		}
+	}
+	return best
+}
+
+func chainNotAfter(chain []*x509.Certificate) time.Time {
+	var end time.Time
+	for i, cert := range chain {
+		if i == 0 || cert.NotAfter.Before(end) {
+			end = cert.NotAfter
+		}
+	}
+	return end
+}
+
+// withVerifiedChain appends certificates of the first verified chain which
+// weren't served
+func withVerifiedChain(served []*x509.Certificate, chains [][]*x509.Certificate) []*x509.Certificate {
+	if len(chains) == 0 {
+		return served
+	}
+	res := append([]*x509.Certificate{}, served...)
+	for _, cert := range chains[0] {
+		if !containsCert(res, cert) {
+			res = append(res, cert)
+		}
+	}
+	return res
+}
+
+func containsCert(certs []*x509.Certificate, cert *x509.Certificate) bool {
+	for _, c := range certs {
+		if c.Equal(cert) {
+			return true
+		}
+	}
+	return false
+}
+
 type validationError struct {
 	wrapped error
 	kind    result.ValidationErrorKind
@@ -0,0 +1,281 @@
+package validator
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/everssl/validator/result"
+)
+
+// testCA is a certificate authority of test PKI
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// newTestCA creates CA certificate valid within given period. Nil parent
+// means self-signed root.
+func newTestCA(t *testing.T, name string, parent *testCA, notBefore, notAfter time.Time) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	ca := &testCA{key: key}
+	if parent == nil {
+		parent = &testCA{cert: tmpl, key: key}
+	}
+	ca.cert = parent.issue(t, tmpl, &key.PublicKey)
+	return ca
+}
+
+func (ca *testCA) issue(t *testing.T, tmpl *x509.Certificate, pub interface{}) *x509.Certificate {
+	t.Helper()
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.SerialNumber = serial
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, pub, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+// leaf issues server certificate for names valid within given period
+func (ca *testCA) leaf(t *testing.T, names []string, notBefore, notAfter time.Time) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ca.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: names[0]},
+		DNSNames:    names,
+		NotBefore:   notBefore,
+		NotAfter:    notAfter,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, &key.PublicKey)
+}
+
+// newTestTrustStore creates exclusive trust store of roots
+func newTestTrustStore(name string, roots ...*x509.Certificate) *TrustStore {
+	store := &TrustStore{
+		name:  name,
+		pool:  x509.NewCertPool(),
+		roots: roots,
+	}
+	for _, root := range roots {
+		store.pool.AddCert(root)
+	}
+	return store
+}
+
+// assertValidationError checks kind of err and that its message contains
+// want. Empty want means no error.
+func assertValidationError(t *testing.T, err result.ValidationError, kind result.ValidationErrorKind, want string) {
+	t.Helper()
+	if want == "" {
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		return
+	}
+	if err == nil {
+		t.Fatalf("error containing %q expected", want)
+	}
+	if err.Kind() != kind || !strings.Contains(err.Error(), want) {
+		t.Errorf("got error %v of kind %v, want %q of kind %v", err, err.Kind(), want, kind)
+	}
+}
+
+func TestVerifyChainWildcard(t *testing.T) {
+	now := time.Now()
+	root := newTestCA(t, "Test Root", nil, now.Add(-time.Hour), now.Add(24*time.Hour))
+	store := newTestTrustStore("test", root.cert)
+	wildcardLeaf := root.leaf(t, []string{"*.example.com"}, now.Add(-time.Hour), now.Add(time.Hour))
+	exactLeaf := root.leaf(t, []string{"www.example.com", "api.example.com"}, now.Add(-time.Hour), now.Add(time.Hour))
+
+	tests := []struct {
+		name       string
+		leaf       *x509.Certificate
+		serverName string
+		wildcard   string
+		wantErr    string
+	}{
+		{
+			name:       "wildcard certificate covers wildcard record",
+			leaf:       wildcardLeaf,
+			serverName: "everssl-wildcard-probe.example.com",
+			wildcard:   "*.example.com",
+		},
+		{
+			name:       "exact names don't cover wildcard record",
+			leaf:       exactLeaf,
+			serverName: "www.example.com",
+			wildcard:   "*.example.com",
+			wantErr:    "doesn't cover wildcard *.example.com",
+		},
+		{
+			name:       "wildcard certificate doesn't cover deeper wildcard",
+			leaf:       wildcardLeaf,
+			serverName: "www.sub.example.com",
+			wildcard:   "*.sub.example.com",
+			wantErr:    "certificate is valid for *.example.com",
+		},
+		{
+			name:       "exact name",
+			leaf:       exactLeaf,
+			serverName: "api.example.com",
+		},
+	}
+
+	v := NewConcurrentValidator(time.Hour, time.Millisecond, time.Second, 1, true)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := v.verifyChain(store, tc.serverName, tc.wildcard, []*x509.Certificate{tc.leaf})
+			assertValidationError(t, err, result.VerificationError, tc.wantErr)
+		})
+	}
+}
+
+func TestCheckExpiration(t *testing.T) {
+	now := time.Now()
+	day := 24 * time.Hour
+	root := newTestCA(t, "Test Root", nil, now.Add(-day), now.Add(365*day))
+	expiring := newTestCA(t, "Test Intermediate", root, now.Add(-day), now.Add(2*day))
+	// cross-signed intermediate with the same subject and key lives longer
+	renewed := &testCA{
+		cert: root.issue(t, &x509.Certificate{
+			Subject:               expiring.cert.Subject,
+			NotBefore:             now.Add(-day),
+			NotAfter:              now.Add(180 * day),
+			IsCA:                  true,
+			BasicConstraintsValid: true,
+			KeyUsage:              x509.KeyUsageCertSign,
+		}, &expiring.key.PublicKey),
+		key: expiring.key,
+	}
+	leaf := expiring.leaf(t, []string{"www.example.com"}, now.Add(-day), now.Add(60*day))
+	expiringLeaf := renewed.leaf(t, []string{"www.example.com"}, now.Add(-day), now.Add(day))
+
+	tests := []struct {
+		name     string
+		served   []*x509.Certificate
+		verified [][]*x509.Certificate
+		wantErr  string
+	}{
+		{
+			name:    "served intermediate",
+			served:  []*x509.Certificate{leaf, expiring.cert},
+			wantErr: `certificate #1 of served chain (subject "CN=Test Intermediate", issuer "CN=Test Root")`,
+		},
+		{
+			name:     "intermediate of verified chain",
+			served:   []*x509.Certificate{leaf},
+			verified: [][]*x509.Certificate{{leaf, expiring.cert, root.cert}},
+			wantErr:  `certificate #1 of verified chain (subject "CN=Test Intermediate", issuer "CN=Test Root")`,
+		},
+		{
+			name:   "longer living chain is chosen",
+			served: []*x509.Certificate{leaf},
+			verified: [][]*x509.Certificate{
+				{leaf, expiring.cert, root.cert},
+				{leaf, renewed.cert, root.cert},
+			},
+		},
+		{
+			name:    "soonest expiring certificate is reported",
+			served:  []*x509.Certificate{expiringLeaf, expiring.cert},
+			wantErr: `leaf certificate (subject "CN=www.example.com", issuer "CN=Test Intermediate")`,
+		},
+	}
+
+	v := NewConcurrentValidator(7*day, time.Millisecond, time.Second, 1, true)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := v.checkExpiration(tc.served, tc.verified)
+			assertValidationError(t, err, result.ExpirationError, tc.wantErr)
+		})
+	}
+}
+
+func TestCheckNotBefore(t *testing.T) {
+	now := time.Now()
+	root := newTestCA(t, "Test Root", nil, now.Add(-time.Hour), now.Add(24*time.Hour))
+	store := newTestTrustStore("test", root.cert)
+	soon := root.leaf(t, []string{"www.example.com"}, now.Add(30*time.Second), now.Add(time.Hour))
+	later := root.leaf(t, []string{"www.example.com"}, now.Add(2*time.Hour), now.Add(3*time.Hour))
+	futureCA := newTestCA(t, "Future Intermediate", root, now.Add(30*time.Second), now.Add(time.Hour))
+	futureChain := []*x509.Certificate{
+		futureCA.leaf(t, []string{"www.example.com"}, now.Add(-time.Hour), now.Add(time.Hour)),
+		futureCA.cert,
+	}
+
+	tests := []struct {
+		name      string
+		certs     []*x509.Certificate
+		skew      time.Duration
+		wantErr   string
+		verifyErr string
+	}{
+		{
+			name:  "within clock skew",
+			certs: []*x509.Certificate{soon},
+			skew:  time.Minute,
+		},
+		{
+			name:      "without clock skew",
+			certs:     []*x509.Certificate{soon},
+			wantErr:   `leaf certificate (subject "CN=www.example.com", issuer "CN=Test Root") is not valid before`,
+			verifyErr: "not yet valid",
+		},
+		{
+			name:      "beyond clock skew",
+			certs:     []*x509.Certificate{later},
+			skew:      time.Minute,
+			wantErr:   "leaf certificate",
+			verifyErr: "not yet valid",
+		},
+		{
+			name:  "intermediate within clock skew",
+			certs: futureChain,
+			skew:  time.Minute,
+		},
+		{
+			name:      "intermediate without clock skew",
+			certs:     futureChain,
+			wantErr:   `certificate #1 of served chain (subject "CN=Future Intermediate", issuer "CN=Test Root")`,
+			verifyErr: "not yet valid",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			v := NewConcurrentValidator(time.Minute, time.Millisecond, time.Second, 1, true).SetClockSkew(tc.skew)
+			assertValidationError(t, v.checkNotBefore(tc.certs), result.NotYetValidError, tc.wantErr)
+			_, err := v.verifyChain(store, "www.example.com", "", tc.certs)
+			assertValidationError(t, err, result.VerificationError, tc.verifyErr)
+		})
+	}
+}
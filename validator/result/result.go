@@ -1,12 +1,36 @@
 package result
 
 import (
+	"time"
+
 	"github.com/mysteriumnetwork/everssl/target"
 )
 
 type ValidationResult struct {
 	Target target.Target
 	Error  ValidationError
+	// Staple describes OCSP response stapled in handshake. It's nil if
+	// handshake didn't happen.
+	Staple *Staple
+	// TrustStore names trust store which certificate chain was verified
+	// against. It's empty if chain wasn't verified.
+	TrustStore string
+}
+
+// OCSP staple statuses
+const (
+	StapleGood    = "good"
+	StapleRevoked = "revoked"
+	StapleUnknown = "unknown"
+	// StapleInvalid means staple can't be parsed or doesn't match the
+	// certificate
+	StapleInvalid = "invalid"
+)
+
+type Staple struct {
+	Present    bool
+	Status     string
+	NextUpdate time.Time
 }
 
 type ValidationErrorKind int
@@ -16,8 +40,29 @@ const (
 	HandshakeError    = ValidationErrorKind(iota)
 	VerificationError = ValidationErrorKind(iota)
 	ExpirationError   = ValidationErrorKind(iota)
+	EnumerationError  = ValidationErrorKind(iota)
+	ProviderError     = ValidationErrorKind(iota)
+	LoadError         = ValidationErrorKind(iota)
+	RevocationError   = ValidationErrorKind(iota)
+	StaplingError     = ValidationErrorKind(iota)
+	NotYetValidError  = ValidationErrorKind(iota)
+	// FallbackError means targets of the zone were taken from cache
+	// because live enumeration failed
+	FallbackError = ValidationErrorKind(iota)
 )
 
+// IsWarning checks if errors of the kind are warnings rather than
+// certificate problems
+func (k ValidationErrorKind) IsWarning() bool {
+	return k == StaplingError || k == FallbackError
+}
+
+// IsZoneProblem checks if errors of the kind belong to zone enumeration
+// rather than to particular target
+func (k ValidationErrorKind) IsZoneProblem() bool {
+	return k == EnumerationError || k == FallbackError
+}
+
 type ValidationError interface {
 	error
 	Unwrap() error
@@ -0,0 +1,256 @@
+package validator
+
+// Certificate revocation checks with OCSP and CRL
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+	"golang.org/x/time/rate"
+
+	"github.com/mysteriumnetwork/everssl/validator/result"
+)
+
+const revocationResponseLimit = 32 * 1024 * 1024
+
+// revocationChecker looks up revocation status of certificates. Requests
+// are paced by limiter shared with handshakes. Downloaded CRLs are shared
+// between checks.
+type revocationChecker struct {
+	client  *http.Client
+	timeout time.Duration
+	limiter *rate.Limiter
+
+	crlMux sync.Mutex
+	crls   map[string]*crlFetch
+}
+
+// crlFetch is a download of CRL, which is complete once done is closed
+type crlFetch struct {
+	done chan struct{}
+	crl  *x509.RevocationList
+	err  error
+}
+
+func newRevocationChecker(timeout time.Duration, limiter *rate.Limiter) *revocationChecker {
+	return &revocationChecker{
+		client:  &http.Client{},
+		timeout: timeout,
+		limiter: limiter,
+		crls:    make(map[string]*crlFetch),
+	}
+}
+
+// check returns RevocationError if leaf certificate is revoked. Fresh
+// stapled OCSP response is used first, then OCSP responders and CRL
+// distribution points of the leaf. Status which can't be determined
+// doesn't fail the check. OCSP responses produced later than skew from now
+// are not trusted.
+func (c *revocationChecker) check(ctx context.Context, certs []*x509.Certificate, stapled []byte, skew time.Duration) result.ValidationError {
+	leaf := certs[0]
+	if len(stapled) == 0 && len(leaf.OCSPServer) == 0 && len(leaf.CRLDistributionPoints) == 0 {
+		return nil
+	}
+
+	issuer := findIssuer(certs)
+	if issuer == nil {
+		log.Printf("Revocation status of %q is unknown: issuer certificate not found", leaf.Subject)
+		return nil
+	}
+
+	if len(stapled) > 0 {
+		resp, err := ocsp.ParseResponseForCert(stapled, leaf, issuer)
+		if err == nil {
+			err = checkOCSPFreshness(resp, skew)
+		}
+		if err != nil {
+			log.Printf("Bad stapled OCSP response for %q: %v", leaf.Subject, err)
+		} else if done, err := ocspVerdict(resp, "stapled OCSP response"); done {
+			return err
+		}
+	}
+
+	for _, server := range leaf.OCSPServer {
+		resp, err := c.queryOCSP(ctx, server, leaf, issuer)
+		if err == nil {
+			err = checkOCSPFreshness(resp, skew)
+		}
+		if err != nil {
+			log.Printf("OCSP query to %s for %q failed: %v", server, leaf.Subject, err)
+			continue
+		}
+		if done, err := ocspVerdict(resp, "OCSP responder "+server); done {
+			return err
+		}
+	}
+
+	for _, dp := range leaf.CRLDistributionPoints {
+		crl, err := c.fetchCRL(ctx, dp, issuer)
+		if err != nil {
+			log.Printf("CRL %s for %q is unavailable: %v", dp, leaf.Subject, err)
+			continue
+		}
+		for _, revoked := range crl.RevokedCertificates {
+			if revoked.SerialNumber.Cmp(leaf.SerialNumber) == 0 {
+				return newValidationError(result.RevocationError,
+					fmt.Errorf("certificate was revoked at %v according to CRL %s", revoked.RevocationTime, dp))
+			}
+		}
+		return nil
+	}
+
+	return nil
+}
+
+// checkOCSPFreshness rejects OCSP response which is past its next update
+// time or produced in the future. Response without next update time is
+// considered fresh.
+func checkOCSPFreshness(resp *ocsp.Response, skew time.Duration) error {
+	now := time.Now()
+	if !resp.NextUpdate.IsZero() && resp.NextUpdate.Before(now) {
+		return fmt.Errorf("response is stale since %v", resp.NextUpdate)
+	}
+	if resp.ThisUpdate.After(now.Add(skew)) {
+		return fmt.Errorf("response is produced in the future at %v", resp.ThisUpdate)
+	}
+	return nil
+}
+
+// ocspVerdict converts OCSP response into check result. Unknown status is
+// not conclusive.
+func ocspVerdict(resp *ocsp.Response, via string) (bool, result.ValidationError) {
+	switch resp.Status {
+	case ocsp.Good:
+		return true, nil
+	case ocsp.Revoked:
+		return true, newValidationError(result.RevocationError,
+			fmt.Errorf("certificate was revoked at %v according to %s", resp.RevokedAt, via))
+	default:
+		return false, nil
+	}
+}
+
+func (c *revocationChecker) queryOCSP(ctx context.Context, server string, leaf, issuer *x509.Certificate) (*ocsp.Response, error) {
+	reqBody, err := ocsp.CreateRequest(leaf, issuer, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cl := context.WithTimeout(ctx, c.timeout)
+	defer cl()
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", server, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/ocsp-request")
+	req.Header.Set("Accept", "application/ocsp-response")
+
+	body, err := c.fetch(req)
+	if err != nil {
+		return nil, err
+	}
+	return ocsp.ParseResponseForCert(body, leaf, issuer)
+}
+
+// fetchCRL returns CRL signed by issuer. Every URL is downloaded once per
+// run, concurrent checks wait for download in progress. Failed download is
+// not retried.
+func (c *revocationChecker) fetchCRL(ctx context.Context, url string, issuer *x509.Certificate) (*x509.RevocationList, error) {
+	c.crlMux.Lock()
+	fetch, ok := c.crls[url]
+	if !ok {
+		fetch = &crlFetch{done: make(chan struct{})}
+		c.crls[url] = fetch
+	}
+	c.crlMux.Unlock()
+
+	if ok {
+		select {
+		case <-fetch.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	} else {
+		fetch.crl, fetch.err = c.downloadCRL(ctx, url)
+		close(fetch.done)
+	}
+	if fetch.err != nil {
+		return nil, fetch.err
+	}
+
+	// CRL may be shared by certificates of different issuers
+	if err := fetch.crl.CheckSignatureFrom(issuer); err != nil {
+		return nil, fmt.Errorf("bad CRL signature: %w", err)
+	}
+	return fetch.crl, nil
+}
+
+func (c *revocationChecker) downloadCRL(ctx context.Context, url string) (*x509.RevocationList, error) {
+	ctx, cl := context.WithTimeout(ctx, c.timeout)
+	defer cl()
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := c.fetch(req)
+	if err != nil {
+		return nil, err
+	}
+	crl, err := x509.ParseRevocationList(body)
+	if err != nil {
+		return nil, fmt.Errorf("bad CRL: %w", err)
+	}
+	return crl, nil
+}
+
+func (c *revocationChecker) fetch(req *http.Request) ([]byte, error) {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("bad HTTP status: %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, revocationResponseLimit))
+}
+
+// findIssuer looks up issuer of leaf certificate among presented chain
+// certificates and, failing that, among verified chains ending in system
+// roots
+func findIssuer(certs []*x509.Certificate) *x509.Certificate {
+	leaf := certs[0]
+	for _, cert := range certs[1:] {
+		if leaf.CheckSignatureFrom(cert) == nil {
+			return cert
+		}
+	}
+
+	opts := x509.VerifyOptions{
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	chains, err := leaf.Verify(opts)
+	if err != nil || len(chains) == 0 || len(chains[0]) < 2 {
+		return nil
+	}
+	return chains[0][1]
+}
@@ -0,0 +1,287 @@
+package validator
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+	"golang.org/x/time/rate"
+
+	"github.com/mysteriumnetwork/everssl/validator/result"
+)
+
+// fakeRevocationPKI is a CA which issues leaf certificate pointing to fake
+// OCSP responder and CRL distribution point
+type fakeRevocationPKI struct {
+	ca, leaf *x509.Certificate
+	caKey    *ecdsa.PrivateKey
+
+	// ocspStatus is returned by responder, negative means HTTP error
+	ocspStatus int
+	// crlRevoked makes CRL list the leaf
+	crlRevoked bool
+	// crlKey signs CRL, CA key is used if nil
+	crlKey *ecdsa.PrivateKey
+
+	ocspRequests, crlRequests atomic.Int32
+}
+
+func newFakeRevocationPKI(t *testing.T) *fakeRevocationPKI {
+	t.Helper()
+	pki := &fakeRevocationPKI{ocspStatus: -1}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ocsp", func(w http.ResponseWriter, r *http.Request) {
+		pki.ocspRequests.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		if _, err := ocsp.ParseRequest(body); err != nil || pki.ocspStatus < 0 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(pki.ocspResponse(t, pki.ocspStatus, time.Now().Add(-time.Minute), time.Now().Add(time.Hour)))
+	})
+	mux.HandleFunc("/crl", func(w http.ResponseWriter, r *http.Request) {
+		pki.crlRequests.Add(1)
+		// let concurrent checks pile up
+		time.Sleep(50 * time.Millisecond)
+		w.Write(pki.crl(t))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	var err error
+	pki.caKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	pki.ca = pki.issue(t, caTmpl, caTmpl, &pki.caKey.PublicKey)
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pki.leaf = pki.issue(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(42),
+		Subject:               pkix.Name{CommonName: "www.example.com"},
+		DNSNames:              []string{"www.example.com"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		OCSPServer:            []string{srv.URL + "/ocsp"},
+		CRLDistributionPoints: []string{srv.URL + "/crl"},
+	}, pki.ca, &leafKey.PublicKey)
+
+	return pki
+}
+
+func (pki *fakeRevocationPKI) issue(t *testing.T, tmpl, parent *x509.Certificate, pub interface{}) *x509.Certificate {
+	t.Helper()
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, pub, pki.caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func (pki *fakeRevocationPKI) ocspResponse(t *testing.T, status int, thisUpdate, nextUpdate time.Time) []byte {
+	t.Helper()
+	resp, err := ocsp.CreateResponse(pki.ca, pki.ca, ocsp.Response{
+		Status:       status,
+		SerialNumber: pki.leaf.SerialNumber,
+		ThisUpdate:   thisUpdate,
+		NextUpdate:   nextUpdate,
+		RevokedAt:    thisUpdate,
+	}, pki.caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func (pki *fakeRevocationPKI) crl(t *testing.T) []byte {
+	t.Helper()
+	tmpl := &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now().Add(-time.Minute),
+		NextUpdate: time.Now().Add(time.Hour),
+	}
+	if pki.crlRevoked {
+		tmpl.RevokedCertificates = []pkix.RevokedCertificate{{
+			SerialNumber:   pki.leaf.SerialNumber,
+			RevocationTime: time.Now().Add(-time.Minute),
+		}}
+	}
+	key := pki.crlKey
+	if key == nil {
+		key = pki.caKey
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, tmpl, pki.ca, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func TestRevocationCheck(t *testing.T) {
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+
+	tests := []struct {
+		name       string
+		staple     func(pki *fakeRevocationPKI) []byte
+		ocspStatus int
+		crlRevoked bool
+		crlKey     *ecdsa.PrivateKey
+		revoked    bool
+		ocspCalls  int32
+	}{
+		{
+			name: "good staple",
+			staple: func(pki *fakeRevocationPKI) []byte {
+				return pki.ocspResponse(t, ocsp.Good, now.Add(-time.Minute), now.Add(time.Hour))
+			},
+			ocspStatus: ocsp.Revoked,
+		},
+		{
+			name: "revoked staple",
+			staple: func(pki *fakeRevocationPKI) []byte {
+				return pki.ocspResponse(t, ocsp.Revoked, now.Add(-time.Minute), now.Add(time.Hour))
+			},
+			ocspStatus: ocsp.Good,
+			revoked:    true,
+		},
+		{
+			name: "stale staple falls back to responder",
+			staple: func(pki *fakeRevocationPKI) []byte {
+				return pki.ocspResponse(t, ocsp.Good, now.Add(-2*time.Hour), now.Add(-time.Hour))
+			},
+			ocspStatus: ocsp.Revoked,
+			revoked:    true,
+			ocspCalls:  1,
+		},
+		{
+			name: "staple from the future falls back to responder",
+			staple: func(pki *fakeRevocationPKI) []byte {
+				return pki.ocspResponse(t, ocsp.Good, now.Add(time.Hour), now.Add(2*time.Hour))
+			},
+			ocspStatus: ocsp.Revoked,
+			revoked:    true,
+			ocspCalls:  1,
+		},
+		{
+			name:       "good responder",
+			ocspStatus: ocsp.Good,
+			crlRevoked: true,
+			ocspCalls:  1,
+		},
+		{
+			name:       "revoked by responder",
+			ocspStatus: ocsp.Revoked,
+			revoked:    true,
+			ocspCalls:  1,
+		},
+		{
+			name:       "revoked by CRL",
+			ocspStatus: -1,
+			crlRevoked: true,
+			revoked:    true,
+			ocspCalls:  1,
+		},
+		{
+			name:       "unknown status falls back to CRL",
+			ocspStatus: ocsp.Unknown,
+			crlRevoked: true,
+			revoked:    true,
+			ocspCalls:  1,
+		},
+		{
+			name:       "not in CRL",
+			ocspStatus: -1,
+			ocspCalls:  1,
+		},
+		{
+			name:       "CRL with bad signature",
+			ocspStatus: -1,
+			crlRevoked: true,
+			crlKey:     otherKey,
+			ocspCalls:  1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pki := newFakeRevocationPKI(t)
+			pki.ocspStatus, pki.crlRevoked, pki.crlKey = tc.ocspStatus, tc.crlRevoked, tc.crlKey
+			var staple []byte
+			if tc.staple != nil {
+				staple = tc.staple(pki)
+			}
+
+			c := newRevocationChecker(time.Second, rate.NewLimiter(rate.Inf, 1))
+			err := c.check(context.Background(), []*x509.Certificate{pki.leaf, pki.ca}, staple, time.Minute)
+			if tc.revoked {
+				if err == nil || err.Kind() != result.RevocationError {
+					t.Errorf("revocation error expected, got %v", err)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if got := pki.ocspRequests.Load(); got != tc.ocspCalls {
+				t.Errorf("got %d OCSP requests, want %d", got, tc.ocspCalls)
+			}
+		})
+	}
+}
+
+func TestRevocationCheckSharesCRL(t *testing.T) {
+	pki := newFakeRevocationPKI(t)
+	pki.crlRevoked = true
+	c := newRevocationChecker(time.Second, rate.NewLimiter(rate.Inf, 1))
+
+	var wg sync.WaitGroup
+	errs := make([]result.ValidationError, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = c.check(context.Background(), []*x509.Certificate{pki.leaf, pki.ca}, nil, 0)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err == nil || err.Kind() != result.RevocationError {
+			t.Errorf("check #%d: revocation error expected, got %v", i, err)
+		}
+	}
+	if got := pki.crlRequests.Load(); got != 1 {
+		t.Errorf("CRL is downloaded %d times, want once", got)
+	}
+}
@@ -0,0 +1,70 @@
+package validator
+
+// OCSP stapling checks
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/mysteriumnetwork/everssl/validator/result"
+)
+
+// inspectStaple describes OCSP response stapled for leaf certificate
+func inspectStaple(certs []*x509.Certificate, stapled []byte) *result.Staple {
+	staple := &result.Staple{
+		Present: len(stapled) > 0,
+	}
+	if !staple.Present {
+		return staple
+	}
+
+	issuer := findIssuer(certs)
+	if issuer == nil {
+		staple.Status = result.StapleInvalid
+		return staple
+	}
+	resp, err := ocsp.ParseResponseForCert(stapled, certs[0], issuer)
+	if err != nil {
+		staple.Status = result.StapleInvalid
+		return staple
+	}
+
+	staple.NextUpdate = resp.NextUpdate
+	switch resp.Status {
+	case ocsp.Good:
+		staple.Status = result.StapleGood
+	case ocsp.Revoked:
+		staple.Status = result.StapleRevoked
+	default:
+		staple.Status = result.StapleUnknown
+	}
+	return staple
+}
+
+// checkStaple reports staple which is missing, not good or stale. Staple
+// without next update time is considered fresh.
+func (v *ConcurrentValidator) checkStaple(staple *result.Staple) result.ValidationError {
+	switch {
+	case !staple.Present:
+		return newValidationError(result.StaplingError, errors.New("OCSP response is not stapled"))
+	case staple.Status != result.StapleGood:
+		return newValidationError(result.StaplingError, fmt.Errorf("stapled OCSP response status is %s", staple.Status))
+	case staple.NextUpdate.IsZero():
+		return nil
+	}
+
+	now := time.Now().Truncate(0)
+	if staple.NextUpdate.Before(now) {
+		return newValidationError(result.StaplingError,
+			fmt.Errorf("stapled OCSP response is stale since %v", staple.NextUpdate))
+	}
+	if staple.NextUpdate.Sub(now) < v.stapleMinValidity {
+		return newValidationError(result.StaplingError,
+			fmt.Errorf("stapled OCSP response will be valid only until %v", staple.NextUpdate))
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package validator
+
+// Plaintext exchanges which upgrade connection to TLS for protocols
+// using STARTTLS-like commands
+
+import (
+	"fmt"
+	"net"
+	"net/textproto"
+	"strings"
+
+	"github.com/mysteriumnetwork/everssl/target"
+)
+
+const (
+	smtpHelloName = "localhost"
+	imapTag       = "a001"
+)
+
+// startTLS performs protocol-specific negotiation on conn. After successful
+// return conn is ready for TLS handshake.
+func startTLS(conn net.Conn, protocol target.Protocol) error {
+	switch protocol {
+	case target.ProtocolTLS:
+		return nil
+	case target.ProtocolSMTP:
+		return startTLSSMTP(conn)
+	case target.ProtocolIMAP:
+		return startTLSIMAP(conn)
+	case target.ProtocolPOP3:
+		return startTLSPOP3(conn)
+	case target.ProtocolFTP:
+		return startTLSFTP(conn)
+	default:
+		return fmt.Errorf("unsupported protocol %q", protocol)
+	}
+}
+
+func startTLSSMTP(conn net.Conn) error {
+	c := textproto.NewConn(conn)
+
+	if _, _, err := c.ReadResponse(220); err != nil {
+		return fmt.Errorf("bad SMTP greeting: %w", err)
+	}
+
+	if err := c.PrintfLine("EHLO %s", smtpHelloName); err != nil {
+		return err
+	}
+	_, msg, err := c.ReadResponse(250)
+	if err != nil {
+		return fmt.Errorf("EHLO failed: %w", err)
+	}
+	supported := false
+	for _, ext := range strings.Split(msg, "\n") {
+		if strings.EqualFold(strings.TrimSpace(ext), "STARTTLS") {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		return fmt.Errorf("server does not advertise STARTTLS extension")
+	}
+
+	if err := c.PrintfLine("STARTTLS"); err != nil {
+		return err
+	}
+	if _, _, err := c.ReadResponse(220); err != nil {
+		return fmt.Errorf("STARTTLS failed: %w", err)
+	}
+
+	return nil
+}
+
+func startTLSIMAP(conn net.Conn) error {
+	c := textproto.NewConn(conn)
+
+	line, err := c.ReadLine()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "* OK") {
+		return fmt.Errorf("bad IMAP greeting: %q", line)
+	}
+
+	if err := c.PrintfLine("%s STARTTLS", imapTag); err != nil {
+		return err
+	}
+	for {
+		line, err = c.ReadLine()
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(line, "* ") {
+			// untagged response, skip it
+			continue
+		}
+		if strings.HasPrefix(line, imapTag+" OK") {
+			return nil
+		}
+		return fmt.Errorf("STARTTLS failed: %q", line)
+	}
+}
+
+func startTLSPOP3(conn net.Conn) error {
+	c := textproto.NewConn(conn)
+
+	line, err := c.ReadLine()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "+OK") {
+		return fmt.Errorf("bad POP3 greeting: %q", line)
+	}
+
+	if err := c.PrintfLine("STLS"); err != nil {
+		return err
+	}
+	line, err = c.ReadLine()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "+OK") {
+		return fmt.Errorf("STLS failed: %q", line)
+	}
+
+	return nil
+}
+
+func startTLSFTP(conn net.Conn) error {
+	c := textproto.NewConn(conn)
+
+	if _, _, err := c.ReadResponse(220); err != nil {
+		return fmt.Errorf("bad FTP greeting: %w", err)
+	}
+
+	if err := c.PrintfLine("AUTH TLS"); err != nil {
+		return err
+	}
+	if _, _, err := c.ReadResponse(234); err != nil {
+		return fmt.Errorf("AUTH TLS failed: %w", err)
+	}
+
+	return nil
+}
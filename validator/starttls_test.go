@@ -0,0 +1,263 @@
+package validator
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/everssl/target"
+)
+
+// dialogStep is a line expected from client followed by server reply.
+// Empty expect means reply is sent without waiting for client, e.g.
+// greeting.
+type dialogStep struct {
+	expect string
+	reply  string
+}
+
+// startFakeServer accepts single connection and plays dialog on it. If
+// cert is given, TLS handshake is served after the dialog.
+func startFakeServer(t *testing.T, dialog []dialogStep, cert *tls.Certificate) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	errCh := make(chan error, 1)
+	t.Cleanup(func() {
+		if err := <-errCh; err != nil {
+			t.Errorf("fake server: %v", err)
+		}
+	})
+
+	go func() {
+		errCh <- func() error {
+			conn, err := ln.Accept()
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+			r := bufio.NewReader(conn)
+			for _, step := range dialog {
+				if step.expect != "" {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return fmt.Errorf("waiting for %q: %w", step.expect, err)
+					}
+					if got := strings.TrimRight(line, "\r\n"); got != step.expect {
+						return fmt.Errorf("got %q, want %q", got, step.expect)
+					}
+				}
+				if _, err := conn.Write([]byte(step.reply)); err != nil {
+					return err
+				}
+			}
+
+			if cert == nil {
+				// let client read the last reply before close
+				r.ReadString('\n')
+				return nil
+			}
+			tlsConn := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{*cert}})
+			if err := tlsConn.Handshake(); err != nil {
+				return fmt.Errorf("TLS handshake: %w", err)
+			}
+			return tlsConn.Close()
+		}()
+	}()
+
+	return ln.Addr().String()
+}
+
+// selfSignedCert creates TLS certificate for name valid for a day
+func selfSignedCert(t *testing.T, name string) *tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}, &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+	}, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestStartTLS(t *testing.T) {
+	tests := []struct {
+		name     string
+		protocol target.Protocol
+		dialog   []dialogStep
+		wantErr  string
+	}{
+		{
+			name:     "SMTP with multi-line greeting and EHLO",
+			protocol: target.ProtocolSMTP,
+			dialog: []dialogStep{
+				{"", "220-mail.example.com ESMTP\r\n220 no UCE\r\n"},
+				{"EHLO localhost", "250-mail.example.com\r\n250-PIPELINING\r\n250-STARTTLS\r\n250 8BITMIME\r\n"},
+				{"STARTTLS", "220 2.0.0 Ready to start TLS\r\n"},
+			},
+		},
+		{
+			name:     "SMTP without STARTTLS",
+			protocol: target.ProtocolSMTP,
+			dialog: []dialogStep{
+				{"", "220 mail.example.com ESMTP\r\n"},
+				{"EHLO localhost", "250-mail.example.com\r\n250 8BITMIME\r\n"},
+			},
+			wantErr: "does not advertise STARTTLS",
+		},
+		{
+			name:     "SMTP STARTTLS refused",
+			protocol: target.ProtocolSMTP,
+			dialog: []dialogStep{
+				{"", "220 mail.example.com ESMTP\r\n"},
+				{"EHLO localhost", "250 STARTTLS\r\n"},
+				{"STARTTLS", "454 4.7.0 TLS not available\r\n"},
+			},
+			wantErr: "STARTTLS failed",
+		},
+		{
+			name:     "SMTP bad greeting",
+			protocol: target.ProtocolSMTP,
+			dialog:   []dialogStep{{"", "554 go away\r\n"}},
+			wantErr:  "bad SMTP greeting",
+		},
+		{
+			name:     "IMAP with untagged responses",
+			protocol: target.ProtocolIMAP,
+			dialog: []dialogStep{
+				{"", "* OK [CAPABILITY IMAP4rev1 STARTTLS] ready\r\n"},
+				{"a001 STARTTLS", "* CAPABILITY IMAP4rev1 STARTTLS\r\n* OK still here\r\na001 OK Begin TLS negotiation now\r\n"},
+			},
+		},
+		{
+			name:     "IMAP STARTTLS refused",
+			protocol: target.ProtocolIMAP,
+			dialog: []dialogStep{
+				{"", "* OK ready\r\n"},
+				{"a001 STARTTLS", "a001 BAD unknown command\r\n"},
+			},
+			wantErr: "STARTTLS failed",
+		},
+		{
+			name:     "IMAP bad greeting",
+			protocol: target.ProtocolIMAP,
+			dialog:   []dialogStep{{"", "* BYE overloaded\r\n"}},
+			wantErr:  "bad IMAP greeting",
+		},
+		{
+			name:     "POP3",
+			protocol: target.ProtocolPOP3,
+			dialog: []dialogStep{
+				{"", "+OK POP3 ready\r\n"},
+				{"STLS", "+OK Begin TLS\r\n"},
+			},
+		},
+		{
+			name:     "POP3 STLS refused",
+			protocol: target.ProtocolPOP3,
+			dialog: []dialogStep{
+				{"", "+OK POP3 ready\r\n"},
+				{"STLS", "-ERR not supported\r\n"},
+			},
+			wantErr: "STLS failed",
+		},
+		{
+			name:     "FTP with multi-line greeting",
+			protocol: target.ProtocolFTP,
+			dialog: []dialogStep{
+				{"", "220-Welcome\r\n220 FTP ready\r\n"},
+				{"AUTH TLS", "234 AUTH TLS successful\r\n"},
+			},
+		},
+		{
+			name:     "FTP AUTH TLS refused",
+			protocol: target.ProtocolFTP,
+			dialog: []dialogStep{
+				{"", "220 FTP ready\r\n"},
+				{"AUTH TLS", "530 Please login with USER and PASS\r\n"},
+			},
+			wantErr: "AUTH TLS failed",
+		},
+		{
+			name:     "plain TLS",
+			protocol: target.ProtocolTLS,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			conn, err := net.Dial("tcp", startFakeServer(t, tc.dialog, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+			err = startTLS(conn, tc.protocol)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				// unblock fake server waiting for client
+				conn.Write([]byte("QUIT\r\n"))
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("got error %v, want %q", err, tc.wantErr)
+			}
+			conn.Write([]byte("QUIT\r\n"))
+		})
+	}
+}
+
+func TestValidateSMTPTarget(t *testing.T) {
+	addr := startFakeServer(t, []dialogStep{
+		{"", "220 mx.example.com ESMTP\r\n"},
+		{"EHLO localhost", "250-mx.example.com\r\n250 STARTTLS\r\n"},
+		{"STARTTLS", "220 Ready\r\n"},
+	}, selfSignedCert(t, "mx.example.com"))
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// target of MX record: checked by exchanger name on SMTP port
+	tgt := target.Target{Domain: "mx.example.com", Address: host, Port: port, Protocol: target.ProtocolSMTP}
+	v := NewConcurrentValidator(time.Hour, time.Millisecond, 5*time.Second, 1, false)
+	results, err := v.Validate(context.Background(), []target.Target{tgt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Error != nil {
+		t.Errorf("unexpected problem: %v", results[0].Error)
+	}
+}
@@ -0,0 +1,131 @@
+package validator
+
+// Custom trust stores
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mysteriumnetwork/everssl/target"
+	"github.com/mysteriumnetwork/everssl/validator/result"
+)
+
+// SystemTrustStore is the name reported for chains verified against system
+// roots
+const SystemTrustStore = "system"
+
+// DefaultTrustStore is used for targets which have no trust store assigned
+// by target or zone, if it's defined
+const DefaultTrustStore = "default"
+
+// TrustStore is a named set of root CA certificates
+type TrustStore struct {
+	name  string
+	pool  *x509.CertPool
+	roots []*x509.Certificate
+}
+
+// LoadTrustStore creates trust store of root certificates read from PEM
+// files. Roots are added to system roots unless exclusive is set.
+func LoadTrustStore(name string, exclusive bool, paths ...string) (*TrustStore, error) {
+	pool := x509.NewCertPool()
+	if !exclusive {
+		sysPool, err := x509.SystemCertPool()
+		if err != nil {
+			return nil, fmt.Errorf("unable to load system roots: %w", err)
+		}
+		pool = sysPool
+	}
+
+	store := &TrustStore{
+		name: name,
+		pool: pool,
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA bundle: %w", err)
+		}
+		certs, err := parsePEMCertificates(data)
+		if err != nil {
+			return nil, fmt.Errorf("bad CA bundle %q: %w", path, err)
+		}
+		if len(certs) == 0 {
+			return nil, fmt.Errorf("no certificates found in CA bundle %q", path)
+		}
+		for _, cert := range certs {
+			pool.AddCert(cert)
+		}
+		store.roots = append(store.roots, certs...)
+	}
+	return store, nil
+}
+
+// Name returns name of the trust store
+func (s *TrustStore) Name() string {
+	return s.name
+}
+
+// verifiedBy names trust store which verified chains end in. Roots of
+// non-exclusive store may come from the system.
+func (s *TrustStore) verifiedBy(chains [][]*x509.Certificate) string {
+	if s == nil {
+		return SystemTrustStore
+	}
+	for _, chain := range chains {
+		if containsCert(s.roots, chain[len(chain)-1]) {
+			return s.name
+		}
+	}
+	return SystemTrustStore
+}
+
+func parsePEMCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return certs, nil
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+}
+
+// trustStoreFor picks trust store of target: the one named by target, the
+// one assigned to the longest matching zone or the default one. Nil means
+// system roots.
+func (v *ConcurrentValidator) trustStoreFor(t target.Target) (*TrustStore, result.ValidationError) {
+	name := t.TrustStore
+	if name == "" {
+		domain := strings.ToLower(strings.TrimSuffix(t.Domain, "."))
+		matched := ""
+		for zone, zoneStore := range v.zoneTrustStores {
+			if (domain == zone || strings.HasSuffix(domain, "."+zone)) && len(zone) > len(matched) {
+				matched, name = zone, zoneStore
+			}
+		}
+	}
+	if name == "" {
+		return v.trustStores[DefaultTrustStore], nil
+	}
+	if name == SystemTrustStore {
+		return nil, nil
+	}
+
+	store, ok := v.trustStores[name]
+	if !ok {
+		return nil, newValidationError(result.VerificationError, fmt.Errorf("unknown trust store %q", name))
+	}
+	return store, nil
+}
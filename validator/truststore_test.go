@@ -0,0 +1,145 @@
+package validator
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/everssl/target"
+	"github.com/mysteriumnetwork/everssl/validator/result"
+)
+
+func TestTrustStoreFor(t *testing.T) {
+	newValidator := func(withDefault bool) *ConcurrentValidator {
+		v := NewConcurrentValidator(time.Hour, time.Millisecond, time.Second, 1, true).
+			AddTrustStore(newTestTrustStore("corp")).
+			AddTrustStore(newTestTrustStore("lab")).
+			SetZoneTrustStore("corp.example.", "corp").
+			SetZoneTrustStore("lab.corp.example", "lab").
+			SetZoneTrustStore("public.corp.example", SystemTrustStore).
+			SetZoneTrustStore("typo.example", "crop")
+		if withDefault {
+			v.AddTrustStore(newTestTrustStore(DefaultTrustStore))
+		}
+		return v
+	}
+
+	tests := []struct {
+		name        string
+		target      target.Target
+		withDefault bool
+		want        string
+		wantErr     string
+	}{
+		{
+			name:   "zone store",
+			target: target.Target{Domain: "www.corp.example"},
+			want:   "corp",
+		},
+		{
+			name:   "zone apex",
+			target: target.Target{Domain: "Corp.Example."},
+			want:   "corp",
+		},
+		{
+			name:   "longest zone wins",
+			target: target.Target{Domain: "www.lab.corp.example"},
+			want:   "lab",
+		},
+		{
+			name:   "target store wins over zone",
+			target: target.Target{Domain: "www.lab.corp.example", TrustStore: "corp"},
+			want:   "corp",
+		},
+		{
+			name:   "system roots by zone",
+			target: target.Target{Domain: "www.public.corp.example"},
+		},
+		{
+			name:   "system roots by target",
+			target: target.Target{Domain: "www.corp.example", TrustStore: SystemTrustStore},
+		},
+		{
+			name:   "no store without default one",
+			target: target.Target{Domain: "www.example.org"},
+		},
+		{
+			name:        "default store",
+			target:      target.Target{Domain: "www.example.org"},
+			withDefault: true,
+			want:        DefaultTrustStore,
+		},
+		{
+			name:        "zone store wins over default",
+			target:      target.Target{Domain: "www.corp.example"},
+			withDefault: true,
+			want:        "corp",
+		},
+		{
+			name:    "unknown target store",
+			target:  target.Target{Domain: "www.corp.example", TrustStore: "missing"},
+			wantErr: `unknown trust store "missing"`,
+		},
+		{
+			name:    "unknown zone store",
+			target:  target.Target{Domain: "www.typo.example"},
+			wantErr: `unknown trust store "crop"`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store, err := newValidator(tc.withDefault).trustStoreFor(tc.target)
+			assertValidationError(t, err, result.VerificationError, tc.wantErr)
+			got := ""
+			if store != nil {
+				got = store.Name()
+			}
+			if got != tc.want {
+				t.Errorf("got trust store %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTrustStoreVerification(t *testing.T) {
+	now := time.Now()
+	privateRoot := newTestCA(t, "Private Root", nil, now.Add(-time.Hour), now.Add(24*time.Hour))
+	otherRoot := newTestCA(t, "Other Root", nil, now.Add(-time.Hour), now.Add(24*time.Hour))
+	leaf := privateRoot.leaf(t, []string{"intranet.corp.example"}, now.Add(-time.Hour), now.Add(time.Hour))
+
+	path := filepath.Join(t.TempDir(), "corp.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: privateRoot.cert.Raw}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	corp, err := LoadTrustStore("corp", true, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := newTestTrustStore("other", otherRoot.cert)
+
+	v := NewConcurrentValidator(time.Hour, time.Millisecond, time.Second, 1, true)
+	certs := []*x509.Certificate{leaf}
+
+	chains, verr := v.verifyChain(corp, "intranet.corp.example", "", certs)
+	assertValidationError(t, verr, result.VerificationError, "")
+	if got := corp.verifiedBy(chains); got != "corp" {
+		t.Errorf("chain is verified by %q, want corp", got)
+	}
+	if got := other.verifiedBy(chains); got != SystemTrustStore {
+		t.Errorf("chain of other root is verified by %q, want %q", got, SystemTrustStore)
+	}
+	if got := (*TrustStore)(nil).verifiedBy(chains); got != SystemTrustStore {
+		t.Errorf("chain without store is verified by %q, want %q", got, SystemTrustStore)
+	}
+
+	_, verr = v.verifyChain(other, "intranet.corp.example", "", certs)
+	assertValidationError(t, verr, result.VerificationError, "verification against trust store other failed")
+
+	if _, err := LoadTrustStore("empty", true, filepath.Join(t.TempDir(), "missing.pem")); err == nil {
+		t.Error("error expected for missing CA bundle")
+	}
+}
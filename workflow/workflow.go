@@ -2,8 +2,11 @@ package workflow
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"github.com/hashicorp/go-multierror"
+
 	"github.com/mysteriumnetwork/everssl/enumerator"
 	"github.com/mysteriumnetwork/everssl/heartbeat"
 	"github.com/mysteriumnetwork/everssl/reporter"
@@ -36,13 +39,25 @@ func NewRunner(enum enumerator.Enumerator, filter StringMatcher, v validator.Val
 
 func (r *Runner) Run(ctx context.Context,
 	zones []string,
-	scanIPv6, ignoreConnectionErrors, ignoreHandshakeErrors, ignoreVerificationErrors, ignoreExpirationErrors bool,
+	scanIPv6 bool,
+	ignoredErrors map[result.ValidationErrorKind]bool,
 ) error {
-	var targets []target.Target
+	var (
+		targets     []target.Target
+		enumResults []result.ValidationResult
+	)
+	badFiles := make(map[string]struct{})
 	for _, zoneName := range zones {
 		zoneTargets, err := r.enumerator.Enumerate(ctx, zoneName, scanIPv6)
-		if err != nil {
-			return fmt.Errorf("unable to enumerate targets for zone %s: %w", zoneName, err)
+		for _, res := range r.enumerationResults(zoneName, err) {
+			// the same file fails enumeration of every zone
+			if res.Error.Kind() == result.LoadError {
+				if _, ok := badFiles[res.Target.Address]; ok {
+					continue
+				}
+				badFiles[res.Target.Address] = struct{}{}
+			}
+			enumResults = append(enumResults, res)
 		}
 
 		for _, target := range zoneTargets {
@@ -56,32 +71,12 @@ func (r *Runner) Run(ctx context.Context,
 	if err != nil {
 		return fmt.Errorf("error: %w", err)
 	}
+	results = append(enumResults, results...)
 
 	var filteredResults []result.ValidationResult
 	for _, res := range results {
-		if res.Error == nil {
+		if res.Error == nil || !ignoredErrors[res.Error.Kind()] {
 			filteredResults = append(filteredResults, res)
-		} else {
-			switch res.Error.Kind() {
-			case result.ConnectionError:
-				if !ignoreConnectionErrors {
-					filteredResults = append(filteredResults, res)
-				}
-			case result.HandshakeError:
-				if !ignoreHandshakeErrors {
-					filteredResults = append(filteredResults, res)
-				}
-			case result.VerificationError:
-				if !ignoreVerificationErrors {
-					filteredResults = append(filteredResults, res)
-				}
-			case result.ExpirationError:
-				if !ignoreExpirationErrors {
-					filteredResults = append(filteredResults, res)
-				}
-			default:
-				filteredResults = append(filteredResults, res)
-			}
 		}
 	}
 	results = nil
@@ -98,3 +93,97 @@ func (r *Runner) Run(ctx context.Context,
 
 	return nil
 }
+
+// enumerationResults converts enumeration error into results attributed
+// to zones which failed, to files which can't be loaded or to targets with
+// problems reported by enumerator.
+// Problems of ignored targets are skipped like the targets themselves.
+func (r *Runner) enumerationResults(zoneName string, err error) []result.ValidationResult {
+	if err == nil {
+		return nil
+	}
+
+	errs := []error{err}
+	if merr, ok := err.(*multierror.Error); ok {
+		errs = merr.Errors
+	}
+
+	res := make([]result.ValidationResult, 0, len(errs))
+	for _, e := range errs {
+		var targetErr *enumerator.TargetError
+		if errors.As(e, &targetErr) {
+			if r.domainFilter.MatchString(targetErr.Target.Domain) {
+				continue
+			}
+			res = append(res, result.ValidationResult{
+				Target: targetErr.Target,
+				Error:  newWorkflowError(result.ProviderError, targetErr.Err),
+			})
+			continue
+		}
+
+		var fileErr *enumerator.FileError
+		if errors.As(e, &fileErr) {
+			res = append(res, result.ValidationResult{
+				Target: target.Target{
+					Domain:   fileErr.Path,
+					Address:  fileErr.Path,
+					Protocol: target.ProtocolFile,
+				},
+				Error: newWorkflowError(result.LoadError, fileErr.Err),
+			})
+			continue
+		}
+
+		var fallbackErr *enumerator.CacheFallbackError
+		if errors.As(e, &fallbackErr) {
+			res = append(res, result.ValidationResult{
+				Target: target.Target{
+					Domain: fallbackErr.Zone,
+				},
+				Error: newWorkflowError(result.FallbackError,
+					fmt.Errorf("unable to enumerate targets for zone %s: %w", fallbackErr.Zone, e)),
+			})
+			continue
+		}
+
+		domain := zoneName
+		var zoneErr *enumerator.ZoneError
+		if errors.As(e, &zoneErr) {
+			domain = zoneErr.Zone
+		}
+		res = append(res, result.ValidationResult{
+			Target: target.Target{
+				Domain: domain,
+			},
+			Error: newWorkflowError(result.EnumerationError,
+				fmt.Errorf("unable to enumerate targets for zone %s: %w", zoneName, e)),
+		})
+	}
+
+	return res
+}
+
+type workflowError struct {
+	wrapped error
+	kind    result.ValidationErrorKind
+}
+
+func newWorkflowError(kind result.ValidationErrorKind, err error) *workflowError {
+	return &workflowError{
+		wrapped: err,
+		kind:    kind,
+	}
+}
+
+func (e *workflowError) Error() string {
+	return e.wrapped.Error()
+}
+
+func (e *workflowError) Unwrap() error {
+	return e.wrapped
+}
+
+func (e *workflowError) Kind() result.ValidationErrorKind {
+	return e.kind
+}